@@ -15,6 +15,8 @@ import (
 
 	"zhurd/internal/adapters/httpapi"
 	"zhurd/internal/config"
+	"zhurd/internal/discovery"
+	"zhurd/internal/job"
 	pq "zhurd/internal/printingqueue"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -22,6 +24,11 @@ import (
 
 var cfgFilePath string
 
+type jobRepo interface {
+	pq.JobStorer
+	job.GetterLister
+}
+
 func init() {
 	const (
 		defaultConfig = "./config.json"
@@ -62,7 +69,36 @@ func main() {
 		defer dbPool.Close()
 	}
 
-	pooler := pq.NewPooler(cfg.Server.QueueBufferSize)
+	var jRepo jobRepo
+	if dbPool != nil {
+		jRepo, err = job.NewPSQL(dbPool)
+	} else {
+		jRepo, err = job.NewMemory()
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	retry := job.RetryPolicy{
+		MaxAttempts: max(cfg.Retry.MaxAttempts, 1),
+		Backoff:     time.Duration(cfg.Retry.BackoffMs) * time.Millisecond,
+		MaxBackoff:  time.Duration(cfg.Retry.MaxBackoffMs) * time.Millisecond,
+	}
+	conn := pq.ConnPolicy{
+		DialTimeout:  time.Duration(cfg.Connection.DialTimeoutMs) * time.Millisecond,
+		WriteTimeout: time.Duration(cfg.Connection.WriteTimeoutMs) * time.Millisecond,
+		Backoff:      time.Duration(cfg.Connection.BackoffMs) * time.Millisecond,
+		MaxBackoff:   time.Duration(cfg.Connection.MaxBackoffMs) * time.Millisecond,
+		// negative interval disables status polling
+		StatusInterval: time.Duration(cfg.Connection.StatusIntervalMs) * time.Millisecond,
+		// negative interval disables flow control, so copies are paced by job timeout
+		FlowInterval:  time.Duration(cfg.Connection.FlowIntervalMs) * time.Millisecond,
+		MaxBuffered:   cfg.Connection.MaxBuffered,
+		VerifyTimeout: time.Duration(cfg.Connection.VerifyTimeoutMs) * time.Millisecond,
+		// negative value disables failover of printer pools
+		FailoverAfter: time.Duration(cfg.Connection.FailoverAfterMs) * time.Millisecond,
+	}
+	pooler := pq.NewPooler(cfg.Server.QueueBufferSize, retry, conn, jRepo)
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	go func() {
@@ -70,7 +106,13 @@ func main() {
 		pooler.Run(ctx)
 	}()
 
-	apiRouter, err := httpapi.New(dbPool, pooler)
+	discoveryOpts := discovery.Options{
+		CIDRs:   cfg.Discovery.CIDRs,
+		Port:    cfg.Discovery.Port,
+		Timeout: time.Duration(cfg.Discovery.TimeoutMs) * time.Millisecond,
+		Workers: cfg.Discovery.Workers,
+	}
+	apiRouter, err := httpapi.New(dbPool, pooler, jRepo, discoveryOpts, cfg.Printers.FileDevices)
 	if err != nil {
 		panic(err)
 	}
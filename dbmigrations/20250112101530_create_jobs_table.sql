@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS jobs (
+	id BIGSERIAL PRIMARY KEY,
+	printer_id BIGINT NOT NULL,
+	quantity INTEGER NOT NULL,
+	timeout BIGINT NOT NULL default 0,
+	document BYTEA NOT NULL,
+	status TEXT NOT NULL,
+	printed INTEGER NOT NULL default 0,
+	last_error TEXT NOT NULL default '',
+	created_at TIMESTAMPTZ NOT NULL default now(),
+	updated_at TIMESTAMPTZ NOT NULL default now()
+);
+CREATE INDEX IF NOT EXISTS jobs_printer_id_status_idx ON jobs (printer_id, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS jobs;
+-- +goose StatementEnd
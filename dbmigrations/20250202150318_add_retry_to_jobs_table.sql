@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS max_attempts INTEGER NOT NULL default 1;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS backoff BIGINT NOT NULL default 0;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS max_backoff BIGINT NOT NULL default 0;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL default 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE jobs DROP COLUMN IF EXISTS attempts;
+ALTER TABLE jobs DROP COLUMN IF EXISTS max_backoff;
+ALTER TABLE jobs DROP COLUMN IF EXISTS backoff;
+ALTER TABLE jobs DROP COLUMN IF EXISTS max_attempts;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE printers ADD COLUMN IF NOT EXISTS tls JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE printers DROP COLUMN IF EXISTS tls;
+-- +goose StatementEnd
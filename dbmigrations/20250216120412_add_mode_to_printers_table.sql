@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE printers ADD COLUMN IF NOT EXISTS mode TEXT NOT NULL default 'persistent';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE printers DROP COLUMN IF EXISTS mode;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE printers ADD COLUMN IF NOT EXISTS verify BOOLEAN NOT NULL default false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE printers DROP COLUMN IF EXISTS verify;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS printer_capabilities (
+	printer_id BIGINT PRIMARY KEY references printers(id) ON DELETE CASCADE,
+	language TEXT NOT NULL default '',
+	dpi INTEGER NOT NULL default 0,
+	print_width INTEGER NOT NULL default 0,
+	media_type TEXT NOT NULL default '',
+	memory_kb INTEGER NOT NULL default 0,
+	options TEXT[] NOT NULL default '{}',
+	model TEXT NOT NULL default '',
+	firmware TEXT NOT NULL default '',
+	detected_at TIMESTAMPTZ
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS printer_capabilities;
+-- +goose StatementEnd
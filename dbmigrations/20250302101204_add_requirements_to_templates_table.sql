@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE templates
+	ADD COLUMN IF NOT EXISTS dpi INTEGER NOT NULL default 0,
+	ADD COLUMN IF NOT EXISTS width INTEGER NOT NULL default 0,
+	ADD COLUMN IF NOT EXISTS requires TEXT[] NOT NULL default '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE templates
+	DROP COLUMN IF EXISTS dpi,
+	DROP COLUMN IF EXISTS width,
+	DROP COLUMN IF EXISTS requires;
+-- +goose StatementEnd
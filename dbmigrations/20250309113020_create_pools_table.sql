@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS pools (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	comment TEXT NOT NULL default ''
+);
+CREATE TABLE IF NOT EXISTS pool_printers (
+	pool_id BIGINT NOT NULL references pools(id) ON DELETE CASCADE,
+	printer_id BIGINT NOT NULL references printers(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL default 0,
+	PRIMARY KEY (pool_id, printer_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pool_printers;
+DROP TABLE IF EXISTS pools;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS routes (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL default '',
+	position INTEGER NOT NULL default 0,
+	station TEXT NOT NULL default '',
+	zone TEXT NOT NULL default '',
+	label_id BIGINT references labels(id) ON DELETE CASCADE,
+	language TEXT NOT NULL default '',
+	dpi INTEGER NOT NULL default 0,
+	requires TEXT[] NOT NULL default '{}',
+	printer_id BIGINT references printers(id) ON DELETE CASCADE,
+	pool_id BIGINT references pools(id) ON DELETE CASCADE,
+	comment TEXT NOT NULL default '',
+	CHECK ((printer_id IS NULL) <> (pool_id IS NULL))
+);
+CREATE INDEX IF NOT EXISTS routes_position_idx ON routes (position, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS routes;
+-- +goose StatementEnd
@@ -0,0 +1,95 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"zhurd/internal/printer"
+)
+
+func showCapabilitiesHandler(svc printer.QuerySvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		printerID, err := getPrinterID(r)
+		if err != nil {
+			slog.Error("cannot get printerID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		pr, err := svc.Get(r.Context(), printerID)
+		if err != nil {
+			if errors.Is(err, printer.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot get printer", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// capabilities are not known until they are set or detected
+		if pr.Capabilities == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(pr.Capabilities)
+	}
+}
+
+func setCapabilitiesHandler(svc printer.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		printerID, err := getPrinterID(r)
+		if err != nil {
+			slog.Error("cannot get printerID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var c printer.Capabilities
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			slog.Error("cannot parse request", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		pr, err := svc.SetCapabilities(r.Context(), printerID, c)
+		if err != nil {
+			writeUpdatePrinterError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(pr.Capabilities)
+	}
+}
+
+func detectCapabilitiesHandler(svc printer.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		printerID, err := getPrinterID(r)
+		if err != nil {
+			slog.Error("cannot get printerID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		pr, err := svc.DetectCapabilities(r.Context(), printerID)
+		if err != nil {
+			if errors.Is(err, printer.ErrDetection) {
+				slog.Warn("cannot detect capabilities", "printerID", printerID, "error", err)
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			writeUpdatePrinterError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(pr.Capabilities)
+	}
+}
@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"zhurd/internal/discovery"
+)
+
+type startDiscovery struct {
+	CIDRs []string `json:"cidrs"`
+}
+
+func startDiscoveryHandler(svc *discovery.Svc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+		// body is optional, configured subnets are scanned without it
+		var sd startDiscovery
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&sd); err != nil {
+				slog.Error("cannot parse request", "error", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		// scan outlives the request, its duration is limited by the number of hosts
+		result, err := svc.Start(context.WithoutCancel(r.Context()), sd.CIDRs)
+		if err != nil {
+			slog.Error("cannot start discovery", "error", err)
+			switch {
+			case errors.Is(err, discovery.ErrRunning):
+				w.WriteHeader(http.StatusConflict)
+			case errors.Is(err, discovery.ErrInvalidCIDR),
+				errors.Is(err, discovery.ErrTooManyHosts),
+				errors.Is(err, discovery.ErrNoCIDR),
+				errors.Is(err, discovery.ErrCIDRNotAllowed):
+				w.WriteHeader(http.StatusBadRequest)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+func showDiscoveryHandler(svc *discovery.Svc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+		result, err := svc.Last(r.Context())
+		if err != nil {
+			if errors.Is(err, discovery.ErrNotRun) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot get discovery result", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(result)
+	}
+}
@@ -0,0 +1,186 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"zhurd/internal/job"
+	pq "zhurd/internal/printingqueue"
+
+	"github.com/gorilla/mux"
+)
+
+func listJobsHandler(svc job.QuerySvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		f, err := getJobFilter(r)
+		if err != nil {
+			slog.Error("cannot parse job filter", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		jobs, err := svc.List(r.Context(), f)
+		if err != nil {
+			if errors.Is(err, job.ErrInvalidFilter) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			slog.Error("cannot list jobs", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(jobs)
+	}
+}
+
+func listDeadLettersHandler(svc job.QuerySvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		f, err := getJobFilter(r)
+		if err != nil {
+			slog.Error("cannot parse job filter", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		jobs, err := svc.ListDeadLetters(r.Context(), f)
+		if err != nil {
+			if errors.Is(err, job.ErrInvalidFilter) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			slog.Error("cannot list dead letters", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(jobs)
+	}
+}
+
+func showJobByIDHandler(svc job.QuerySvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		jobID, err := getJobID(r)
+		if err != nil {
+			slog.Error("cannot parse jobID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		j, err := svc.Get(r.Context(), jobID)
+		if err != nil {
+			if errors.Is(err, job.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot get job", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(j)
+	}
+}
+
+func redriveJobByIDHandler(svc job.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		jobID, err := getJobID(r)
+		if err != nil {
+			slog.Error("cannot get jobID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := svc.Redrive(r.Context(), jobID); err != nil {
+			if errors.Is(err, job.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, job.ErrNotDeadLetter) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			if errors.Is(err, pq.ErrPrinterNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, pq.ErrQueueFull) {
+				w.Header().Set("Retry-After", retryAfterSec)
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			slog.Error("cannot redrive job", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func getJobID(r *http.Request) (int64, error) {
+	vars := mux.Vars(r)
+	val := vars["jobID"]
+	return strconv.ParseInt(val, 10, 64)
+}
+
+func cancelJobByIDHandler(svc job.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		jobID, err := getJobID(r)
+		if err != nil {
+			slog.Error("cannot get jobID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := svc.Cancel(r.Context(), jobID); err != nil {
+			if errors.Is(err, job.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, job.ErrFinished) || errors.Is(err, pq.ErrJobNotQueued) {
+				// job may finish while it is canceled
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			slog.Error("cannot cancel job", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// getJobFilter reads status, limit and offset of the job list from the
+// query, missing ones are left zero.
+func getJobFilter(r *http.Request) (job.Filter, error) {
+	query := r.URL.Query()
+	f := job.Filter{Status: job.Status(query.Get("status"))}
+	if val := query.Get("limit"); val != "" {
+		limit, err := strconv.Atoi(val)
+		if err != nil {
+			return job.Filter{}, err
+		}
+		f.Limit = limit
+	}
+	if val := query.Get("offset"); val != "" {
+		offset, err := strconv.Atoi(val)
+		if err != nil {
+			return job.Filter{}, err
+		}
+		f.Offset = offset
+	}
+	return f, nil
+}
@@ -3,11 +3,14 @@ package httpapi
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 
 	"zhurd/internal/label"
+	pq "zhurd/internal/printingqueue"
+	"zhurd/internal/route"
 
 	"github.com/gorilla/mux"
 )
@@ -104,6 +107,13 @@ func deleteLabelByIDHandler(svc label.CommandSvc) func(w http.ResponseWriter, r
 	}
 }
 
+// retryAfterSec is sent to clients when the queue cannot accept the job.
+const retryAfterSec = "5"
+
+type enqueuedJob struct {
+	ID int64 `json:"job_id"`
+}
+
 func enqueueLabelHandler(svc label.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -121,18 +131,39 @@ func enqueueLabelHandler(svc label.CommandSvc) func(w http.ResponseWriter, r *ht
 			return
 		}
 
-		if err := svc.Enqueue(r.Context(), labelID, enqueueLabel); err != nil {
-			if errors.Is(err, label.ValidationError) {
+		jobID, err := svc.Enqueue(r.Context(), labelID, enqueueLabel)
+		if err != nil {
+			switch {
+			case errors.Is(err, label.ValidationError),
+				errors.Is(err, label.MissingTemplateError),
+				errors.Is(err, label.IncompatibleTemplateError),
+				errors.Is(err, label.MissingPlaceholderError):
 				slog.Error("validation error", "error", err)
 				w.WriteHeader(http.StatusBadRequest)
 				return
+			case errors.Is(err, label.ErrNotFound), errors.Is(err, pq.ErrPrinterNotFound), errors.Is(err, pq.ErrPoolNotFound),
+				errors.Is(err, route.ErrNoRoute):
+				w.WriteHeader(http.StatusNotFound)
+				return
+			case errors.Is(err, pq.ErrQueueFull):
+				slog.Warn("cannot enqueue label", "error", err)
+				w.Header().Set("Retry-After", retryAfterSec)
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			case errors.Is(err, pq.ErrNotRunning):
+				slog.Warn("cannot enqueue label", "error", err)
+				w.Header().Set("Retry-After", retryAfterSec)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
 			}
 			slog.Error("cannot enqueue label", "error", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		w.WriteHeader(http.StatusNoContent)
+		w.Header().Set("Location", fmt.Sprintf("/v1/jobs/%d", jobID))
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(enqueuedJob{ID: jobID})
 	}
 }
 
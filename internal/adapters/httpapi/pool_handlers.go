@@ -0,0 +1,180 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"zhurd/internal/pool"
+	pq "zhurd/internal/printingqueue"
+)
+
+// poolView is the pool with live health of its members, members that
+// have no running queue are omitted.
+type poolView struct {
+	pool.Pool
+	Members []pq.State `json:"members"`
+}
+
+func listPoolsHandler(svc pool.QuerySvc, queue *pq.Pooler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		pools, err := svc.List(r.Context())
+		if err != nil {
+			slog.Error("cannot list pools", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		states, err := queue.Snapshot(r.Context())
+		if err != nil {
+			slog.Warn("cannot get queues snapshot, pools are listed without health", "error", err)
+		}
+
+		views := make([]poolView, 0, len(pools))
+		for _, p := range pools {
+			views = append(views, newPoolView(p, states))
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(views)
+	}
+}
+
+func showPoolByIDHandler(svc pool.QuerySvc, queue *pq.Pooler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		poolID, err := getPoolID(r)
+		if err != nil {
+			slog.Error("cannot get poolID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		p, err := svc.Get(r.Context(), poolID)
+		if err != nil {
+			if errors.Is(err, pool.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot get pool", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		states, err := queue.Snapshot(r.Context())
+		if err != nil {
+			slog.Warn("cannot get queues snapshot, pool is shown without health", "poolID", poolID, "error", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(newPoolView(p, states))
+	}
+}
+
+// newPoolView joins the pool with states of its members in the order of
+// members.
+func newPoolView(p pool.Pool, states []pq.State) poolView {
+	view := poolView{Pool: p, Members: []pq.State{}}
+	for _, printerID := range p.PrinterIDs {
+		for _, st := range states {
+			if st.PrinterID == printerID {
+				view.Members = append(view.Members, st)
+			}
+		}
+	}
+	return view
+}
+
+func createPoolHandler(svc pool.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+		var cp pool.CreatePool
+		if err := json.NewDecoder(r.Body).Decode(&cp); err != nil {
+			slog.Error("cannot parse request", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		p, err := svc.Create(r.Context(), cp)
+		if err != nil {
+			writePoolError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(p)
+	}
+}
+
+func replacePoolByIDHandler(svc pool.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		poolID, err := getPoolID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var cp pool.CreatePool
+		if err := json.NewDecoder(r.Body).Decode(&cp); err != nil {
+			slog.Error("cannot parse request", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		p, err := svc.Replace(r.Context(), poolID, cp)
+		if err != nil {
+			writePoolError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(p)
+	}
+}
+
+func writePoolError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, pool.ValidationError), errors.Is(err, pool.ErrUnknownPrinter):
+		slog.Error("validation error", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, pool.ErrNameTaken):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, pool.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		slog.Error("cannot store pool", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func deletePoolByIDHandler(svc pool.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		poolID, err := getPoolID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := svc.Delete(r.Context(), poolID); err != nil {
+			if errors.Is(err, pool.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot delete pool", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func getPoolID(r *http.Request) (int64, error) {
+	vars := mux.Vars(r)
+	val := vars["poolID"]
+	return strconv.ParseInt(val, 10, 64)
+}
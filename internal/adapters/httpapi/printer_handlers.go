@@ -9,9 +9,17 @@ import (
 
 	"github.com/gorilla/mux"
 	"zhurd/internal/printer"
+	pq "zhurd/internal/printingqueue"
 )
 
-func listPrintersHandler(svc printer.QuerySvc) func(w http.ResponseWriter, r *http.Request) {
+// printerView is the printer with live health of its queue, health is
+// omitted if the queue is not running.
+type printerView struct {
+	printer.Printer
+	Health *pq.State `json:"health,omitempty"`
+}
+
+func listPrintersHandler(svc printer.QuerySvc, queue *pq.Pooler) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		printers, err := svc.List(r.Context())
@@ -20,13 +28,62 @@ func listPrintersHandler(svc printer.QuerySvc) func(w http.ResponseWriter, r *ht
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		states, err := queue.Snapshot(r.Context())
+		if err != nil {
+			slog.Warn("cannot get queues snapshot, printers are listed without health", "error", err)
+		}
 
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(printers)
+		json.NewEncoder(w).Encode(printerViews(printers, states, false))
 	}
 }
 
-func showPrinterByIDHandler(svc printer.QuerySvc) func(w http.ResponseWriter, r *http.Request) {
+// listUnhealthyPrintersHandler lists only printers that are not healthy, so
+// the whole fleet can be watched with a single request.
+func listUnhealthyPrintersHandler(svc printer.QuerySvc, queue *pq.Pooler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		states, err := queue.Unhealthy(r.Context())
+		if err != nil {
+			slog.Error("cannot get unhealthy queues", "error", err)
+			if errors.Is(err, pq.ErrNotRunning) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		printers, err := svc.List(r.Context())
+		if err != nil {
+			slog.Error("cannot list printers", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(printerViews(printers, states, true))
+	}
+}
+
+// printerViews joins printers with states of their queues, only printers
+// that have state are returned if onlyWithState is set.
+func printerViews(printers []printer.Printer, states []pq.State, onlyWithState bool) []printerView {
+	byID := make(map[int64]*pq.State, len(states))
+	for i := range states {
+		byID[states[i].PrinterID] = &states[i]
+	}
+	views := make([]printerView, 0, len(printers))
+	for _, p := range printers {
+		st := byID[p.ID]
+		if st == nil && onlyWithState {
+			continue
+		}
+		views = append(views, printerView{Printer: p, Health: st})
+	}
+	return views
+}
+
+func showPrinterByIDHandler(svc printer.QuerySvc, queue *pq.Pooler) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		printerID, err := getPrinterID(r)
@@ -46,9 +103,15 @@ func showPrinterByIDHandler(svc printer.QuerySvc) func(w http.ResponseWriter, r
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		view := printerView{Printer: pr}
+		if st, err := queue.State(r.Context(), printerID); err == nil {
+			view.Health = &st
+		} else {
+			slog.Warn("cannot get queue state, printer is shown without health", "printerID", printerID, "error", err)
+		}
 
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(pr)
+		json.NewEncoder(w).Encode(view)
 	}
 }
 
@@ -80,6 +143,111 @@ func createPrinterHandler(svc printer.CommandSvc) func(w http.ResponseWriter, r
 	}
 }
 
+// createPrintersHandler registers printers in bulk, nothing is registered
+// if any of them is invalid or cannot be stored.
+func createPrintersHandler(svc printer.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+		var cps []printer.CreatePrinter
+		if err := json.NewDecoder(r.Body).Decode(&cps); err != nil {
+			slog.Error("cannot parse request", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		printers, err := svc.CreateMany(r.Context(), cps)
+		if err != nil {
+			if errors.Is(err, printer.ValidationError) {
+				slog.Error("validation error", "error", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			slog.Error("cannot create printers", "registered", len(printers), "error", err)
+			if len(printers) > 0 {
+				// printers are stored, their queues start with the next start
+				w.WriteHeader(http.StatusServiceUnavailable)
+				json.NewEncoder(w).Encode(printers)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(printers)
+	}
+}
+
+func replacePrinterByIDHandler(svc printer.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		printerID, err := getPrinterID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var cp printer.CreatePrinter
+		if err := json.NewDecoder(r.Body).Decode(&cp); err != nil {
+			slog.Error("cannot parse request", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		pr, err := svc.Replace(r.Context(), printerID, cp)
+		if err != nil {
+			writeUpdatePrinterError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(pr)
+	}
+}
+
+func updatePrinterByIDHandler(svc printer.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		printerID, err := getPrinterID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var up printer.UpdatePrinter
+		if err := json.NewDecoder(r.Body).Decode(&up); err != nil {
+			slog.Error("cannot parse request", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		pr, err := svc.Update(r.Context(), printerID, up)
+		if err != nil {
+			writeUpdatePrinterError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(pr)
+	}
+}
+
+func writeUpdatePrinterError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, printer.ValidationError):
+		slog.Error("validation error", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, printer.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, pq.ErrJobsPending):
+		w.WriteHeader(http.StatusConflict)
+	default:
+		slog.Error("cannot update printer", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 func deletePrinterByIDHandler(svc printer.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
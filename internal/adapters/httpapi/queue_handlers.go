@@ -0,0 +1,241 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	pq "zhurd/internal/printingqueue"
+)
+
+func listQueuesHandler(queue *pq.Pooler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		states, err := queue.Snapshot(r.Context())
+		if err != nil {
+			slog.Error("cannot get queues snapshot", "error", err)
+			if errors.Is(err, pq.ErrNotRunning) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(states)
+	}
+}
+
+func showQueueByPrinterIDHandler(queue *pq.Pooler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		printerID, err := getPrinterID(r)
+		if err != nil {
+			slog.Error("cannot parse printerID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		state, err := queue.State(r.Context(), printerID)
+		if err != nil {
+			if errors.Is(err, pq.ErrPrinterNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot get queue state", "printerID", printerID, "error", err)
+			if errors.Is(err, pq.ErrNotRunning) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(state)
+	}
+}
+
+func showPrinterStatusHandler(queue *pq.Pooler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		printerID, err := getPrinterID(r)
+		if err != nil {
+			slog.Error("cannot parse printerID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		status, err := queue.PrinterStatus(r.Context(), printerID)
+		if err != nil {
+			if errors.Is(err, pq.ErrPrinterNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot get printer status", "printerID", printerID, "error", err)
+			if errors.Is(err, pq.ErrNotRunning) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
+// controlQueueHandler pauses, drains or resumes the queue of the printer
+// with control func of the pooler and shows the state of the queue.
+func controlQueueHandler(control func(context.Context, int64) (pq.State, error)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		printerID, err := getPrinterID(r)
+		if err != nil {
+			slog.Error("cannot parse printerID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		state, err := control(r.Context(), printerID)
+		if err != nil {
+			if errors.Is(err, pq.ErrPrinterNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot control queue", "printerID", printerID, "error", err)
+			if errors.Is(err, pq.ErrNotRunning) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(state)
+	}
+}
+
+func listPendingJobsHandler(queue *pq.Pooler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		printerID, err := getPrinterID(r)
+		if err != nil {
+			slog.Error("cannot parse printerID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		jobs, err := queue.Pending(r.Context(), printerID)
+		if err != nil {
+			if errors.Is(err, pq.ErrPrinterNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot list pending jobs", "printerID", printerID, "error", err)
+			if errors.Is(err, pq.ErrNotRunning) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(jobs)
+	}
+}
+
+func moveJobToFrontHandler(queue *pq.Pooler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		printerID, err := getPrinterID(r)
+		if err != nil {
+			slog.Error("cannot parse printerID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		jobID, err := getJobID(r)
+		if err != nil {
+			slog.Error("cannot parse jobID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := queue.MoveToFront(r.Context(), printerID, jobID); err != nil {
+			if errors.Is(err, pq.ErrPrinterNotFound) || errors.Is(err, pq.ErrJobNotQueued) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot move job to front", "printerID", printerID, "jobID", jobID, "error", err)
+			if errors.Is(err, pq.ErrNotRunning) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type migrateJobs struct {
+	ToPrinterID int64   `json:"to_printer_id"`
+	JobIDs      []int64 `json:"job_ids"`
+}
+
+func migrateJobsHandler(queue *pq.Pooler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		printerID, err := getPrinterID(r)
+		if err != nil {
+			slog.Error("cannot parse printerID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var mj migrateJobs
+		if err := json.NewDecoder(r.Body).Decode(&mj); err != nil {
+			slog.Error("cannot decode body", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if mj.ToPrinterID < 1 {
+			slog.Error("validation error", "error", "to_printer_id is required")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		jobs, err := queue.Migrate(r.Context(), printerID, mj.ToPrinterID, mj.JobIDs...)
+		if err != nil {
+			switch {
+			case errors.Is(err, pq.ErrPrinterNotFound), errors.Is(err, pq.ErrJobNotQueued):
+				w.WriteHeader(http.StatusNotFound)
+				return
+			case errors.Is(err, pq.ErrIncompatible):
+				w.WriteHeader(http.StatusConflict)
+				return
+			case errors.Is(err, pq.ErrQueueFull):
+				slog.Warn("cannot migrate jobs", "error", err)
+				w.Header().Set("Retry-After", retryAfterSec)
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			case errors.Is(err, pq.ErrNotRunning):
+				slog.Warn("cannot migrate jobs", "error", err)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			slog.Error("cannot migrate jobs", "printerID", printerID, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(jobs)
+	}
+}
@@ -0,0 +1,180 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"zhurd/internal/route"
+)
+
+func listRoutesHandler(svc route.QuerySvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		routes, err := svc.List(r.Context())
+		if err != nil {
+			slog.Error("cannot list routes", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(routes)
+	}
+}
+
+func showRouteByIDHandler(svc route.QuerySvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		routeID, err := getRouteID(r)
+		if err != nil {
+			slog.Error("cannot get routeID", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		rt, err := svc.Get(r.Context(), routeID)
+		if err != nil {
+			if errors.Is(err, route.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot get route", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(rt)
+	}
+}
+
+// resolveRouteHandler shows where the request with the given attributes
+// would be printed, so routes can be checked without printing.
+func resolveRouteHandler(svc route.QuerySvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		query := r.URL.Query()
+		req := route.Request{
+			Station: query.Get("station"),
+			Zone:    query.Get("zone"),
+		}
+		if val := query.Get("label_id"); val != "" {
+			labelID, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				slog.Error("cannot parse label_id", "error", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			req.LabelID = labelID
+		}
+
+		target, err := svc.Resolve(r.Context(), req)
+		if err != nil {
+			if errors.Is(err, route.ErrNoRoute) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot resolve route", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(target)
+	}
+}
+
+func createRouteHandler(svc route.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+		var cr route.CreateRoute
+		if err := json.NewDecoder(r.Body).Decode(&cr); err != nil {
+			slog.Error("cannot parse request", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		rt, err := svc.Create(r.Context(), cr)
+		if err != nil {
+			writeRouteError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(rt)
+	}
+}
+
+func replaceRouteByIDHandler(svc route.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		routeID, err := getRouteID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var cr route.CreateRoute
+		if err := json.NewDecoder(r.Body).Decode(&cr); err != nil {
+			slog.Error("cannot parse request", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		rt, err := svc.Replace(r.Context(), routeID, cr)
+		if err != nil {
+			writeRouteError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(rt)
+	}
+}
+
+func writeRouteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, route.ValidationError), errors.Is(err, route.ErrUnknownTarget):
+		slog.Error("validation error", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, route.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		slog.Error("cannot store route", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func deleteRouteByIDHandler(svc route.CommandSvc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		routeID, err := getRouteID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := svc.Delete(r.Context(), routeID); err != nil {
+			if errors.Is(err, route.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			slog.Error("cannot delete route", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func getRouteID(r *http.Request) (int64, error) {
+	vars := mux.Vars(r)
+	val := vars["routeID"]
+	return strconv.ParseInt(val, 10, 64)
+}
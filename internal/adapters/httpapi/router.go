@@ -8,9 +8,13 @@ import (
 	"net/http"
 	"time"
 
+	"zhurd/internal/discovery"
+	"zhurd/internal/job"
 	"zhurd/internal/label"
+	"zhurd/internal/pool"
 	"zhurd/internal/printer"
 	pq "zhurd/internal/printingqueue"
+	"zhurd/internal/route"
 
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -21,12 +25,22 @@ type printerRepo interface {
 	printer.StorerDeleter
 }
 
+type poolRepo interface {
+	pool.GetterLister
+	pool.StorerDeleter
+}
+
+type routeRepo interface {
+	route.GetterLister
+	route.StorerDeleter
+}
+
 type labelRepo interface {
 	label.GetterLister
 	label.StorerDeleter
 }
 
-func New(dbPool *pgxpool.Pool, queue *pq.Pooler) (*mux.Router, error) {
+func New(dbPool *pgxpool.Pool, queue *pq.Pooler, jRepo job.GetterLister, discoveryOpts discovery.Options, fileDevices []string) (*mux.Router, error) {
 	r := mux.NewRouter()
 	r.HandleFunc("/", defaultHandler)
 	v1r := r.PathPrefix("/v1").Subrouter()
@@ -51,15 +65,87 @@ func New(dbPool *pgxpool.Pool, queue *pq.Pooler) (*mux.Router, error) {
 	if err != nil {
 		return nil, err
 	}
-	queue.Add(printers...)
-	printerCommandSvc := printer.NewCommandSvc(pRepo, queue)
+	if err := queue.Add(ctx, printers...); err != nil {
+		return nil, err
+	}
+	printerCommandSvc := printer.NewCommandSvc(pRepo, queue, fileDevices)
 	printerQuerySvc := printer.NewQuerySvc(pRepo)
 
-	v1r.HandleFunc("/printers", listPrintersHandler(printerQuerySvc)).Methods("GET")
-	v1r.HandleFunc("/printers/{printerID}", showPrinterByIDHandler(printerQuerySvc)).Methods("GET")
+	v1r.HandleFunc("/printers", listPrintersHandler(printerQuerySvc, queue)).Methods("GET")
+	// registered before /printers/{printerID} to take precedence
+	v1r.HandleFunc("/printers/unhealthy", listUnhealthyPrintersHandler(printerQuerySvc, queue)).Methods("GET")
+	v1r.HandleFunc("/printers/{printerID}", showPrinterByIDHandler(printerQuerySvc, queue)).Methods("GET")
+	v1r.HandleFunc("/printers/{printerID}/status", showPrinterStatusHandler(queue)).Methods("GET")
+	v1r.HandleFunc("/printers/{printerID}/capabilities", showCapabilitiesHandler(printerQuerySvc)).Methods("GET")
 
 	v1r.HandleFunc("/printers", createPrinterHandler(printerCommandSvc)).Methods("POST")
+	v1r.HandleFunc("/printers/bulk", createPrintersHandler(printerCommandSvc)).Methods("POST")
+	v1r.HandleFunc("/printers/{printerID}", replacePrinterByIDHandler(printerCommandSvc)).Methods("PUT")
+	v1r.HandleFunc("/printers/{printerID}", updatePrinterByIDHandler(printerCommandSvc)).Methods("PATCH")
 	v1r.HandleFunc("/printers/{printerID}", deletePrinterByIDHandler(printerCommandSvc)).Methods("DELETE")
+	v1r.HandleFunc("/printers/{printerID}/capabilities", setCapabilitiesHandler(printerCommandSvc)).Methods("PUT")
+	v1r.HandleFunc("/printers/{printerID}/capabilities/detect", detectCapabilitiesHandler(printerCommandSvc)).Methods("POST")
+
+	// pool
+	var plRepo poolRepo
+	if dbPool != nil {
+		plRepo, err = pool.NewPSQL(dbPool)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		plRepo, err = pool.NewMemory()
+		if err != nil {
+			return nil, err
+		}
+	}
+	pools, err := plRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := queue.SetPools(ctx, pools...); err != nil {
+		return nil, err
+	}
+	poolCommandSvc := pool.NewCommandSvc(plRepo, pRepo, queue)
+	poolQuerySvc := pool.NewQuerySvc(plRepo)
+
+	v1r.HandleFunc("/pools", listPoolsHandler(poolQuerySvc, queue)).Methods("GET")
+	v1r.HandleFunc("/pools/{poolID}", showPoolByIDHandler(poolQuerySvc, queue)).Methods("GET")
+
+	v1r.HandleFunc("/pools", createPoolHandler(poolCommandSvc)).Methods("POST")
+	v1r.HandleFunc("/pools/{poolID}", replacePoolByIDHandler(poolCommandSvc)).Methods("PUT")
+	v1r.HandleFunc("/pools/{poolID}", deletePoolByIDHandler(poolCommandSvc)).Methods("DELETE")
+
+	// route
+	var rRepo routeRepo
+	if dbPool != nil {
+		rRepo, err = route.NewPSQL(dbPool)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		rRepo, err = route.NewMemory()
+		if err != nil {
+			return nil, err
+		}
+	}
+	routeCommandSvc := route.NewCommandSvc(rRepo, pRepo, plRepo)
+	routeQuerySvc := route.NewQuerySvc(rRepo, pRepo, plRepo)
+
+	v1r.HandleFunc("/routes", listRoutesHandler(routeQuerySvc)).Methods("GET")
+	// registered before /routes/{routeID} to take precedence
+	v1r.HandleFunc("/routes/resolve", resolveRouteHandler(routeQuerySvc)).Methods("GET")
+	v1r.HandleFunc("/routes/{routeID}", showRouteByIDHandler(routeQuerySvc)).Methods("GET")
+
+	v1r.HandleFunc("/routes", createRouteHandler(routeCommandSvc)).Methods("POST")
+	v1r.HandleFunc("/routes/{routeID}", replaceRouteByIDHandler(routeCommandSvc)).Methods("PUT")
+	v1r.HandleFunc("/routes/{routeID}", deleteRouteByIDHandler(routeCommandSvc)).Methods("DELETE")
+
+	// discovery
+	discoverySvc := discovery.NewSvc(discoveryOpts, pRepo)
+
+	v1r.HandleFunc("/discovery", showDiscoveryHandler(discoverySvc)).Methods("GET")
+	v1r.HandleFunc("/discovery", startDiscoveryHandler(discoverySvc)).Methods("POST")
 
 	// label
 	var lRepo labelRepo
@@ -74,7 +160,7 @@ func New(dbPool *pgxpool.Pool, queue *pq.Pooler) (*mux.Router, error) {
 			return nil, err
 		}
 	}
-	labelCommandSvc := label.NewCommandSvc(lRepo, queue)
+	labelCommandSvc := label.NewCommandSvc(lRepo, queue, routeQuerySvc)
 	labelQuerySvc := label.NewQuerySvc(lRepo)
 
 	v1r.HandleFunc("/labels", listLabelsHandler(labelQuerySvc)).Methods("GET")
@@ -93,6 +179,31 @@ func New(dbPool *pgxpool.Pool, queue *pq.Pooler) (*mux.Router, error) {
 	// enque label
 	v1r.HandleFunc("/labels/{labelID}/enqueue", enqueueLabelHandler(labelCommandSvc)).Methods("POST")
 
+	// job
+	jobCommandSvc := job.NewCommandSvc(jRepo, queue)
+	jobQuerySvc := job.NewQuerySvc(jRepo)
+
+	v1r.HandleFunc("/jobs", listJobsHandler(jobQuerySvc)).Methods("GET")
+	v1r.HandleFunc("/jobs/{jobID}", showJobByIDHandler(jobQuerySvc)).Methods("GET")
+
+	v1r.HandleFunc("/jobs/{jobID}", cancelJobByIDHandler(jobCommandSvc)).Methods("DELETE")
+
+	// dead letters
+	v1r.HandleFunc("/dead-letters", listDeadLettersHandler(jobQuerySvc)).Methods("GET")
+
+	v1r.HandleFunc("/dead-letters/{jobID}/redrive", redriveJobByIDHandler(jobCommandSvc)).Methods("POST")
+
+	// queue
+	v1r.HandleFunc("/queues", listQueuesHandler(queue)).Methods("GET")
+	v1r.HandleFunc("/queues/{printerID}", showQueueByPrinterIDHandler(queue)).Methods("GET")
+
+	v1r.HandleFunc("/printers/{printerID}/queue", listPendingJobsHandler(queue)).Methods("GET")
+	v1r.HandleFunc("/printers/{printerID}/queue/{jobID:[0-9]+}:front", moveJobToFrontHandler(queue)).Methods("POST")
+	v1r.HandleFunc("/printers/{printerID}/queue:migrate", migrateJobsHandler(queue)).Methods("POST")
+	v1r.HandleFunc("/printers/{printerID}/queue:pause", controlQueueHandler(queue.Pause)).Methods("POST")
+	v1r.HandleFunc("/printers/{printerID}/queue:drain", controlQueueHandler(queue.Drain)).Methods("POST")
+	v1r.HandleFunc("/printers/{printerID}/queue:resume", controlQueueHandler(queue.Resume)).Methods("POST")
+
 	r.Use(loggingMiddleware)
 
 	return r, nil
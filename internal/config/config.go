@@ -20,6 +20,42 @@ type Server struct {
 	QueueBufferSize    int    `json:"queue_buffer_size"`
 }
 
+// Retry contains default retry policy for print jobs.
+type Retry struct {
+	MaxAttempts  int `json:"max_attempts"`
+	BackoffMs    int `json:"backoff_ms"`
+	MaxBackoffMs int `json:"max_backoff_ms"`
+}
+
+// Connection contains timeouts of connections to printers, backoff of
+// reconnect after failures, period of status polling, flow control,
+// confirmation of printed labels and failover of printer pools.
+type Connection struct {
+	DialTimeoutMs    int `json:"dial_timeout_ms"`
+	WriteTimeoutMs   int `json:"write_timeout_ms"`
+	BackoffMs        int `json:"backoff_ms"`
+	MaxBackoffMs     int `json:"max_backoff_ms"`
+	StatusIntervalMs int `json:"status_interval_ms"`
+	FlowIntervalMs   int `json:"flow_interval_ms"`
+	MaxBuffered      int `json:"max_buffered"`
+	VerifyTimeoutMs  int `json:"verify_timeout_ms"`
+	FailoverAfterMs  int `json:"failover_after_ms"`
+}
+
+// Discovery contains subnets scanned for printers and limits of the scan.
+type Discovery struct {
+	CIDRs     []string `json:"cidrs"`
+	Port      int      `json:"port"`
+	TimeoutMs int      `json:"timeout_ms"`
+	Workers   int      `json:"workers"`
+}
+
+// Printers contains glob patterns of files printers can be registered with,
+// e.g. /dev/usb/lp* for USB printers.
+type Printers struct {
+	FileDevices []string `json:"file_devices"`
+}
+
 // Databse contains all configuration for database connection.
 type Database struct {
 	Host     string `json:"host"`
@@ -47,9 +83,13 @@ func (cfg *Database) ConnectionString() string {
 
 // Config is a high-level struct that contains all configuration.
 type Config struct {
-	Server   Server   `json:"server"`
-	Logger   Logger   `json:"logger"`
-	Database Database `json:"database"`
+	Server     Server     `json:"server"`
+	Logger     Logger     `json:"logger"`
+	Database   Database   `json:"database"`
+	Retry      Retry      `json:"retry"`
+	Connection Connection `json:"connection"`
+	Discovery  Discovery  `json:"discovery"`
+	Printers   Printers   `json:"printers"`
 }
 
 // Load configuration from file.
@@ -25,6 +25,31 @@ func TestLoad(t *testing.T) {
     "password": "passwordsecretdb",
     "name": "zhurd",
     "ssl_mode": "disable"
+  },
+  "retry": {
+    "max_attempts": 5,
+    "backoff_ms": 1000,
+    "max_backoff_ms": 60000
+  },
+  "connection": {
+    "dial_timeout_ms": 5000,
+    "write_timeout_ms": 30000,
+    "backoff_ms": 500,
+    "max_backoff_ms": 30000,
+    "status_interval_ms": 10000,
+    "flow_interval_ms": 250,
+    "max_buffered": 2,
+    "verify_timeout_ms": 30000,
+    "failover_after_ms": 30000
+  },
+  "discovery": {
+    "cidrs": ["192.168.0.0/24"],
+    "port": 9100,
+    "timeout_ms": 1000,
+    "workers": 64
+  },
+  "printers": {
+    "file_devices": ["/dev/usb/lp*"]
   }
 }
     `)
@@ -70,6 +95,51 @@ func TestLoad(t *testing.T) {
 	if cfg.Database.SSLMode != "disable" {
 		t.Errorf("expected %s, got %s\n", "disable", cfg.Database.SSLMode)
 	}
+	if cfg.Retry.MaxAttempts != 5 {
+		t.Errorf("expected %d, got %d\n", 5, cfg.Retry.MaxAttempts)
+	}
+	if cfg.Retry.BackoffMs != 1000 {
+		t.Errorf("expected %d, got %d\n", 1000, cfg.Retry.BackoffMs)
+	}
+	if cfg.Retry.MaxBackoffMs != 60000 {
+		t.Errorf("expected %d, got %d\n", 60000, cfg.Retry.MaxBackoffMs)
+	}
+	if cfg.Connection.DialTimeoutMs != 5000 {
+		t.Errorf("expected %d, got %d\n", 5000, cfg.Connection.DialTimeoutMs)
+	}
+	if cfg.Connection.WriteTimeoutMs != 30000 {
+		t.Errorf("expected %d, got %d\n", 30000, cfg.Connection.WriteTimeoutMs)
+	}
+	if cfg.Connection.BackoffMs != 500 {
+		t.Errorf("expected %d, got %d\n", 500, cfg.Connection.BackoffMs)
+	}
+	if cfg.Connection.MaxBackoffMs != 30000 {
+		t.Errorf("expected %d, got %d\n", 30000, cfg.Connection.MaxBackoffMs)
+	}
+	if cfg.Connection.StatusIntervalMs != 10000 {
+		t.Errorf("expected %d, got %d\n", 10000, cfg.Connection.StatusIntervalMs)
+	}
+	if cfg.Connection.FlowIntervalMs != 250 {
+		t.Errorf("expected %d, got %d\n", 250, cfg.Connection.FlowIntervalMs)
+	}
+	if cfg.Connection.MaxBuffered != 2 {
+		t.Errorf("expected %d, got %d\n", 2, cfg.Connection.MaxBuffered)
+	}
+	if cfg.Connection.VerifyTimeoutMs != 30000 {
+		t.Errorf("expected %d, got %d\n", 30000, cfg.Connection.VerifyTimeoutMs)
+	}
+	if cfg.Connection.FailoverAfterMs != 30000 {
+		t.Errorf("expected %d, got %d\n", 30000, cfg.Connection.FailoverAfterMs)
+	}
+	if len(cfg.Discovery.CIDRs) != 1 || cfg.Discovery.CIDRs[0] != "192.168.0.0/24" {
+		t.Errorf("expected %v, got %v\n", []string{"192.168.0.0/24"}, cfg.Discovery.CIDRs)
+	}
+	if cfg.Discovery.Port != 9100 {
+		t.Errorf("expected %d, got %d\n", 9100, cfg.Discovery.Port)
+	}
+	if len(cfg.Printers.FileDevices) != 1 || cfg.Printers.FileDevices[0] != "/dev/usb/lp*" {
+		t.Errorf("expected %v, got %v\n", []string{"/dev/usb/lp*"}, cfg.Printers.FileDevices)
+	}
 	if cfg.Database.ConnectionString() != "host=localhost port=5432 user=zhurd password=passwordsecretdb dbname=zhurd sslmode=disable" {
 		t.Errorf("expected %s, got %s\n",
 			"host=localhost port=5432 user=zhurd password=passwordsecretdb dbname=zhurd sslmode=disable",
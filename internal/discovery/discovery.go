@@ -0,0 +1,157 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"zhurd/internal/printer"
+)
+
+var (
+	ErrRunning = errors.New("discovery is already running")
+	ErrNotRun  = errors.New("discovery has not been run")
+	ErrNoCIDR  = errors.New("no cidr to scan")
+	// ErrCIDRNotAllowed is returned for subnets outside of configured ones,
+	// so clients cannot make the server probe other networks.
+	ErrCIDRNotAllowed = errors.New("cidr is not within configured subnets")
+)
+
+// Statuses of the scan.
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusStopped = "stopped"
+)
+
+// Result is the state of the last scan.
+type Result struct {
+	Status     string      `json:"status"`
+	CIDRs      []string    `json:"cidrs"`
+	Hosts      int         `json:"hosts"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+type Lister interface {
+	List(context.Context) ([]printer.Printer, error)
+}
+
+// Svc runs one scan at a time and keeps the result of the last one.
+type Svc struct {
+	opts     Options
+	printers Lister
+
+	mu   sync.Mutex
+	last *Result
+}
+
+func NewSvc(opts Options, printers Lister) *Svc {
+	return &Svc{
+		opts:     opts.withDefaults(),
+		printers: printers,
+	}
+}
+
+// Start scans the subnets in background, configured subnets are scanned
+// if none is given and given ones must be within them. The scan is stopped
+// when ctx is done.
+func (svc *Svc) Start(ctx context.Context, cidrs []string) (Result, error) {
+	if len(cidrs) == 0 {
+		cidrs = svc.opts.CIDRs
+	}
+	if len(cidrs) == 0 {
+		return Result{}, ErrNoCIDR
+	}
+	hosts, err := Hosts(cidrs)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := svc.allowed(cidrs); err != nil {
+		return Result{}, err
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	if svc.last != nil && svc.last.Status == StatusRunning {
+		return Result{}, ErrRunning
+	}
+	svc.last = &Result{
+		Status:     StatusRunning,
+		CIDRs:      cidrs,
+		Hosts:      len(hosts),
+		StartedAt:  time.Now(),
+		Candidates: []Candidate{},
+	}
+	result := *svc.last
+
+	slog.Info("discovery: scan is started", "cidrs", cidrs, "hosts", len(hosts))
+	go func() {
+		candidates := Scan(ctx, hosts, svc.opts)
+		status := StatusDone
+		if ctx.Err() != nil {
+			status = StatusStopped
+		}
+		slog.Info("discovery: scan is finished", "status", status, "candidates", len(candidates))
+
+		svc.mu.Lock()
+		defer svc.mu.Unlock()
+		now := time.Now()
+		svc.last.Status = status
+		svc.last.FinishedAt = &now
+		svc.last.Candidates = candidates
+	}()
+	return result, nil
+}
+
+// allowed checks that every subnet is within one of configured subnets.
+func (svc *Svc) allowed(cidrs []string) error {
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidCIDR, err)
+		}
+		if !slices.ContainsFunc(svc.opts.CIDRs, func(configured string) bool {
+			c, err := netip.ParsePrefix(configured)
+			return err == nil && c.Bits() <= prefix.Bits() && c.Contains(prefix.Addr())
+		}) {
+			return fmt.Errorf("%w: %s", ErrCIDRNotAllowed, cidr)
+		}
+	}
+	return nil
+}
+
+// Last returns the result of the last scan, candidates that are already
+// registered as printers are marked.
+func (svc *Svc) Last(ctx context.Context) (Result, error) {
+	svc.mu.Lock()
+	if svc.last == nil {
+		svc.mu.Unlock()
+		return Result{}, ErrNotRun
+	}
+	result := *svc.last
+	result.Candidates = append([]Candidate{}, svc.last.Candidates...)
+	svc.mu.Unlock()
+
+	printers, err := svc.printers.List(ctx)
+	if err != nil {
+		return Result{}, fmt.Errorf("cannot list printers: %w", err)
+	}
+	registered := map[string]bool{}
+	for _, p := range printers {
+		// candidates are found on raw port
+		if u, err := printer.ParseAddr(p.Addr); err == nil && u.Scheme == "tcp" {
+			registered[u.Host] = true
+		}
+	}
+	for i := range result.Candidates {
+		result.Candidates[i].Registered = registered[result.Candidates[i].Addr]
+	}
+	return result, nil
+}
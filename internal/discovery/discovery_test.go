@@ -0,0 +1,69 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"zhurd/internal/printer"
+)
+
+func TestSvc(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	go serveIdentification(l)
+	port := l.Addr().(*net.TCPAddr).Port
+
+	repo, err := printer.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if _, err := NewSvc(Options{Port: port}, repo).Start(context.Background(), nil); !errors.Is(err, ErrNoCIDR) {
+		t.Errorf("expected: %v, got: %v\n", ErrNoCIDR, err)
+	}
+	svc := NewSvc(Options{CIDRs: []string{"127.0.0.0/30"}, Port: port}, repo)
+	if _, err := svc.Last(context.Background()); !errors.Is(err, ErrNotRun) {
+		t.Errorf("expected: %v, got: %v\n", ErrNotRun, err)
+	}
+	for _, cidrs := range [][]string{{"10.0.0.0/24"}, {"127.0.0.0/29"}, {"127.0.0.1/32", "127.0.1.1/32"}} {
+		if _, err := svc.Start(context.Background(), cidrs); !errors.Is(err, ErrCIDRNotAllowed) {
+			t.Errorf("%v: expected: %v, got: %v\n", cidrs, ErrCIDRNotAllowed, err)
+		}
+	}
+
+	if _, err := svc.Start(context.Background(), []string{"127.0.0.1/32"}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	var result Result
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		result, err = svc.Last(context.Background())
+		if err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		if result.Status != StatusRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if result.Status != StatusDone || len(result.Candidates) != 1 || result.Candidates[0].Registered {
+		t.Fatalf("expected one unregistered candidate, got: %+v\n", result)
+	}
+
+	p := printer.New("ZPL", result.Candidates[0].Addr, "")
+	if err := repo.Store(context.Background(), &p); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	result, err = svc.Last(context.Background())
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if !result.Candidates[0].Registered {
+		t.Errorf("expected registered candidate, got: %+v\n", result.Candidates[0])
+	}
+}
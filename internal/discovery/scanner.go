@@ -0,0 +1,163 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sync"
+	"time"
+
+	"zhurd/internal/printer"
+)
+
+var (
+	ErrInvalidCIDR  = errors.New("invalid cidr")
+	ErrTooManyHosts = errors.New("too many hosts to scan")
+)
+
+// maxHosts limits the number of hosts of one scan, it is enough for /20.
+const maxHosts = 4096
+
+// Options configures the scan, zero values are replaced by DefaultOptions.
+type Options struct {
+	// CIDRs are scanned when the scan is started without subnets.
+	CIDRs []string
+	Port  int
+	// Timeout limits connect and identification of every host.
+	Timeout time.Duration
+	// Workers is the number of hosts probed at once.
+	Workers int
+}
+
+var DefaultOptions = Options{
+	Port:    9100,
+	Timeout: time.Second,
+	Workers: 64,
+}
+
+func (o Options) withDefaults() Options {
+	if o.Port <= 0 {
+		o.Port = DefaultOptions.Port
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultOptions.Timeout
+	}
+	if o.Workers <= 0 {
+		o.Workers = DefaultOptions.Workers
+	}
+	return o
+}
+
+// Candidate is a host that accepts connections on the printer port, it is
+// identified if it responds to ~HI. Capabilities can be passed on bulk
+// registration as they are.
+type Candidate struct {
+	Addr         string                `json:"addr"`
+	Type         string                `json:"type,omitempty"`
+	Capabilities *printer.Capabilities `json:"capabilities,omitempty"`
+	Error        string                `json:"error,omitempty"`
+	// Registered is set if a printer with the same address already exists.
+	Registered bool `json:"registered"`
+}
+
+// Hosts returns addresses of the subnets without duplicates, network and
+// broadcast addresses of IPv4 subnets are skipped.
+func Hosts(cidrs []string) ([]netip.Addr, error) {
+	var hosts []netip.Addr
+	seen := map[netip.Addr]bool{}
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCIDR, err)
+		}
+		prefix = prefix.Masked()
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits > 12 {
+			return nil, fmt.Errorf("%w: %s", ErrTooManyHosts, cidr)
+		}
+		first, last := prefix.Addr(), lastAddr(prefix)
+		if prefix.Addr().Is4() && hostBits > 1 {
+			first, last = first.Next(), last.Prev()
+		}
+		for addr := first; addr.IsValid() && addr.Compare(last) <= 0; addr = addr.Next() {
+			if seen[addr] {
+				continue
+			}
+			seen[addr] = true
+			hosts = append(hosts, addr)
+			if len(hosts) > maxHosts {
+				return nil, fmt.Errorf("%w: more than %d", ErrTooManyHosts, maxHosts)
+			}
+		}
+	}
+	return hosts, nil
+}
+
+// lastAddr returns the last address of the masked prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bs := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(bs)*8; i++ {
+		bs[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(bs)
+	return addr
+}
+
+// Scan probes the hosts in parallel and returns candidates in the order of hosts.
+func Scan(ctx context.Context, hosts []netip.Addr, opts Options) []Candidate {
+	opts = opts.withDefaults()
+	found := make([]*Candidate, len(hosts))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(opts.Workers, len(hosts)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				found[i] = probe(ctx, netip.AddrPortFrom(hosts[i], uint16(opts.Port)), opts.Timeout)
+			}
+		}()
+	}
+	for i := range hosts {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	candidates := []Candidate{}
+	for _, c := range found {
+		if c != nil {
+			candidates = append(candidates, *c)
+		}
+	}
+	return candidates
+}
+
+// probe connects to the host and asks it to identify itself, it returns
+// nil if the host does not accept connections.
+func probe(ctx context.Context, addr netip.AddrPort, timeout time.Duration) *Candidate {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	p := printer.New(printer.TypeZPL, addr.String(), "")
+	if err := p.Connect(ctx); err != nil {
+		return nil
+	}
+	defer p.Close()
+
+	c := &Candidate{Addr: addr.String()}
+	capabilities, err := p.QueryCapabilities(ctx)
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	c.Type = printer.TypeZPL
+	c.Capabilities = &capabilities
+	return c
+}
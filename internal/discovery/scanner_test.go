@@ -0,0 +1,105 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestHosts(t *testing.T) {
+	ucs := []struct {
+		desc          string
+		cidrs         []string
+		expected      []string
+		expectedError error
+	}{
+		{
+			desc:     "network and broadcast are skipped",
+			cidrs:    []string{"192.168.0.0/30"},
+			expected: []string{"192.168.0.1", "192.168.0.2"},
+		},
+		{
+			desc:     "single host",
+			cidrs:    []string{"192.168.0.7/32"},
+			expected: []string{"192.168.0.7"},
+		},
+		{
+			desc:     "host bits are masked and duplicates skipped",
+			cidrs:    []string{"192.168.0.5/30", "192.168.0.6/32"},
+			expected: []string{"192.168.0.5", "192.168.0.6"},
+		},
+		{
+			desc:          "too large",
+			cidrs:         []string{"10.0.0.0/16"},
+			expectedError: ErrTooManyHosts,
+		},
+		{
+			desc:          "invalid",
+			cidrs:         []string{"192.168.0.0"},
+			expectedError: ErrInvalidCIDR,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			hosts, err := Hosts(us.cidrs)
+			if !errors.Is(err, us.expectedError) {
+				t.Fatalf("expected: %v, got: %v\n", us.expectedError, err)
+			}
+			if len(hosts) != len(us.expected) {
+				t.Fatalf("expected: %v, got: %v\n", us.expected, hosts)
+			}
+			for i := range hosts {
+				if hosts[i].String() != us.expected[i] {
+					t.Errorf("expected: %v, got: %v\n", us.expected, hosts)
+				}
+			}
+		})
+	}
+}
+
+// serveIdentification answers ~HI like ZPL printer.
+func serveIdentification(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			buf := make([]byte, 256)
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			if bytes.Contains(buf[:n], []byte("~HI")) {
+				conn.Write([]byte("\x02ZT410-200dpi,V75.20.01Z,8,8176KB\x03\r\n"))
+			}
+		}()
+	}
+}
+
+func TestScan(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	go serveIdentification(l)
+	port := l.Addr().(*net.TCPAddr).Port
+
+	// nobody listens on 127.0.0.2
+	hosts := []netip.Addr{netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("127.0.0.2")}
+	candidates := Scan(context.Background(), hosts, Options{Port: port})
+	if len(candidates) != 1 {
+		t.Fatalf("expected: %d, got: %+v\n", 1, candidates)
+	}
+	c := candidates[0]
+	if c.Addr != l.Addr().String() || c.Type != "ZPL" || c.Capabilities == nil || c.Capabilities.DPI != 203 {
+		t.Errorf("expected identified printer at %s, got: %+v\n", l.Addr(), c)
+	}
+}
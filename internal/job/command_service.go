@@ -0,0 +1,57 @@
+package job
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrFinished      = errors.New("job is already finished")
+	ErrNotDeadLetter = errors.New("job is not in dead letters")
+)
+
+type Getter interface {
+	Get(context.Context, int64) (Job, error)
+}
+
+type Queue interface {
+	Cancel(context.Context, Job) error
+	Redrive(context.Context, Job) error
+}
+
+type CommandSvc struct {
+	db    Getter
+	queue Queue
+}
+
+func NewCommandSvc(db Getter, queue Queue) CommandSvc {
+	return CommandSvc{
+		db:    db,
+		queue: queue,
+	}
+}
+
+// Cancel removes pending job from the queue, job that is printing at the
+// moment stops after the current copy.
+func (svc CommandSvc) Cancel(ctx context.Context, jobID int64) error {
+	j, err := svc.db.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if j.IsFinished() {
+		return ErrFinished
+	}
+	return svc.queue.Cancel(ctx, j)
+}
+
+// Redrive puts the job from dead letters back to the queue with reset attempts.
+func (svc CommandSvc) Redrive(ctx context.Context, jobID int64) error {
+	j, err := svc.db.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if j.Status != StatusDeadLetter {
+		return ErrNotDeadLetter
+	}
+	return svc.queue.Redrive(ctx, j)
+}
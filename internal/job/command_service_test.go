@@ -0,0 +1,145 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type TestQueue struct {
+	canceled int
+	redriven int
+}
+
+func (q *TestQueue) Cancel(ctx context.Context, j Job) error {
+	q.canceled++
+	return nil
+}
+
+func (q *TestQueue) Redrive(ctx context.Context, j Job) error {
+	q.redriven++
+	return nil
+}
+
+func TestCancel(t *testing.T) {
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	queued := New(1, 10, 0)
+	if err := repo.Store(context.Background(), &queued); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	done := New(1, 1, 0)
+	if err := repo.Store(context.Background(), &done); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	done.Status = StatusDone
+	if err := repo.Update(context.Background(), &done); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	ucs := []struct {
+		desc             string
+		jobID            int64
+		expectedErr      error
+		expectedCanceled int
+	}{
+		{
+			desc:             "happy path",
+			jobID:            queued.ID,
+			expectedErr:      nil,
+			expectedCanceled: 1,
+		},
+		{
+			desc:             "finished job",
+			jobID:            done.ID,
+			expectedErr:      ErrFinished,
+			expectedCanceled: 0,
+		},
+		{
+			desc:             "job with ID does not exist",
+			jobID:            100,
+			expectedErr:      ErrNotFound,
+			expectedCanceled: 0,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			q := &TestQueue{}
+			svc := NewCommandSvc(repo, q)
+
+			err := svc.Cancel(context.Background(), us.jobID)
+			if !errors.Is(err, us.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+			if q.canceled != us.expectedCanceled {
+				t.Errorf("expected canceled: %d, got: %d\n", us.expectedCanceled, q.canceled)
+			}
+		})
+	}
+}
+
+func TestRedrive(t *testing.T) {
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	queued := New(1, 10, 0)
+	if err := repo.Store(context.Background(), &queued); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	dead := New(1, 1, 0)
+	if err := repo.Store(context.Background(), &dead); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	dead.Status = StatusDeadLetter
+	dead.Attempts = 3
+	if err := repo.Update(context.Background(), &dead); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	ucs := []struct {
+		desc             string
+		jobID            int64
+		expectedErr      error
+		expectedRedriven int
+	}{
+		{
+			desc:             "happy path",
+			jobID:            dead.ID,
+			expectedErr:      nil,
+			expectedRedriven: 1,
+		},
+		{
+			desc:             "job is not in dead letters",
+			jobID:            queued.ID,
+			expectedErr:      ErrNotDeadLetter,
+			expectedRedriven: 0,
+		},
+		{
+			desc:             "job with ID does not exist",
+			jobID:            100,
+			expectedErr:      ErrNotFound,
+			expectedRedriven: 0,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			q := &TestQueue{}
+			svc := NewCommandSvc(repo, q)
+
+			err := svc.Redrive(context.Background(), us.jobID)
+			if !errors.Is(err, us.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+			if q.redriven != us.expectedRedriven {
+				t.Errorf("expected redriven: %d, got: %d\n", us.expectedRedriven, q.redriven)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package job
+
+import (
+	"math"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusQueued   Status = "queued"
+	StatusPrinting Status = "printing"
+	StatusDone     Status = "done"
+	// job is sent, but the printer did not confirm that all labels are printed
+	StatusUnconfirmed Status = "unconfirmed"
+	StatusFailed      Status = "failed"
+	StatusCanceled    Status = "canceled"
+	StatusScheduled   Status = "scheduled"
+	StatusExpired     Status = "expired"
+	// job has exhausted all attempts
+	StatusDeadLetter Status = "dead_letter"
+)
+
+// RetryPolicy defines how many times the job is attempted and the delay
+// between attempts, the delay grows exponentially up to MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts int           `json:"max_attempts"`
+	Backoff     time.Duration `json:"backoff"`
+	MaxBackoff  time.Duration `json:"max_backoff"`
+}
+
+// Delay returns the delay before the next attempt after the given number
+// of failed attempts.
+func (r RetryPolicy) Delay(attempts int) time.Duration {
+	delay := r.Backoff
+	for i := 1; i < attempts; i++ {
+		if (r.MaxBackoff > 0 && delay >= r.MaxBackoff) || delay > math.MaxInt64/2 {
+			break
+		}
+		delay *= 2
+	}
+	if r.MaxBackoff > 0 && delay > r.MaxBackoff {
+		return r.MaxBackoff
+	}
+	return delay
+}
+
+// Job is a persisted print task, Document holds the label already rendered
+// for the printer type, so the job can be restored without its label.
+// PoolID is set if the job is enqueued to a pool, PrinterID is the member
+// of the pool the job is dispatched to.
+type Job struct {
+	ID        int64         `json:"id"`
+	PrinterID int64         `json:"printer_id"`
+	PoolID    *int64        `json:"pool_id,omitempty"`
+	Quantity  int           `json:"quantity"`
+	Timeout   time.Duration `json:"timeout"`
+	Priority  int           `json:"priority"`
+	NotBefore *time.Time    `json:"not_before,omitempty"`
+	ExpiresAt *time.Time    `json:"expires_at,omitempty"`
+	Retry     RetryPolicy   `json:"retry"`
+	Document  []byte        `json:"-"`
+	Status    Status        `json:"status"`
+	Printed   int           `json:"printed"`
+	Attempts  int           `json:"attempts"`
+	LastError string        `json:"last_error"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+func New(printerID int64, quantity int, timeout time.Duration) Job {
+	return Job{
+		PrinterID: printerID,
+		Quantity:  quantity,
+		Timeout:   timeout,
+		Status:    StatusQueued,
+	}
+}
+
+func (j Job) IsFinished() bool {
+	return j.Status != StatusQueued && j.Status != StatusPrinting && j.Status != StatusScheduled
+}
+
+// IsDue reports whether the job may be printed at the moment.
+func (j Job) IsDue(now time.Time) bool {
+	return j.NotBefore == nil || !j.NotBefore.After(now)
+}
+
+func (j Job) IsExpired(now time.Time) bool {
+	return j.ExpiresAt != nil && !j.ExpiresAt.After(now)
+}
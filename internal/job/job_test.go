@@ -0,0 +1,30 @@
+package job
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 10,
+		Backoff:     time.Second,
+		MaxBackoff:  10 * time.Second,
+	}
+	tcs := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: time.Second},
+		{attempts: 2, expected: 2 * time.Second},
+		{attempts: 3, expected: 4 * time.Second},
+		{attempts: 4, expected: 8 * time.Second},
+		{attempts: 5, expected: 10 * time.Second},
+		{attempts: 50, expected: 10 * time.Second},
+	}
+	for _, tc := range tcs {
+		if got := policy.Delay(tc.attempts); got != tc.expected {
+			t.Errorf("attempts %d: expected %s, got %s\n", tc.attempts, tc.expected, got)
+		}
+	}
+}
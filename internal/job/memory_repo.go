@@ -0,0 +1,113 @@
+package job
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("record not found")
+)
+
+// Memory keeps jobs as values instead of json, because document is not
+// a part of json representation of the job.
+type Memory struct {
+	m      map[int64]Job
+	nextID int64
+	mu     sync.RWMutex
+}
+
+func NewMemory() (*Memory, error) {
+	return &Memory{
+		m:      make(map[int64]Job),
+		nextID: 1,
+		mu:     sync.RWMutex{},
+	}, nil
+}
+
+func (m *Memory) Store(ctx context.Context, j *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if j.ID == 0 {
+		if _, ok := m.m[m.nextID]; ok {
+			panic("could not generate unique ID for job")
+		}
+		j.ID = m.nextID
+		m.nextID += 1
+	}
+	now := time.Now()
+	j.CreatedAt = now
+	j.UpdatedAt = now
+	m.m[j.ID] = *j
+
+	return nil
+}
+
+func (m *Memory) Update(ctx context.Context, j *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.m[j.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	j.UpdatedAt = time.Now()
+	// job of a pool may move to another member
+	stored.PrinterID = j.PrinterID
+	stored.Status = j.Status
+	stored.Printed = j.Printed
+	stored.Attempts = j.Attempts
+	stored.NotBefore = j.NotBefore
+	stored.LastError = j.LastError
+	stored.UpdatedAt = j.UpdatedAt
+	m.m[j.ID] = stored
+
+	return nil
+}
+
+func (m *Memory) ListUnfinished(ctx context.Context, printerID int64) ([]Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	jobs := []Job{}
+	for _, j := range m.m {
+		if j.PrinterID == printerID && !j.IsFinished() {
+			jobs = append(jobs, j)
+		}
+	}
+	slices.SortFunc(jobs, func(a, b Job) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return jobs, nil
+}
+
+func (m *Memory) List(ctx context.Context, f Filter) ([]Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	jobs := []Job{}
+	for _, j := range m.m {
+		if f.Status != "" && j.Status != f.Status {
+			continue
+		}
+		j.Document = nil
+		jobs = append(jobs, j)
+	}
+	slices.SortFunc(jobs, func(a, b Job) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	start := min(f.Offset, len(jobs))
+	end := min(start+f.Limit, len(jobs))
+	return jobs[start:end], nil
+}
+
+func (m *Memory) Get(ctx context.Context, id int64) (Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	j, ok := m.m[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return j, nil
+}
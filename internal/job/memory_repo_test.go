@@ -0,0 +1,98 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryRoundTrip(t *testing.T) {
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	ctx := context.Background()
+
+	notBefore := time.Now().Add(time.Hour)
+	queued := New(1, 3, time.Second)
+	queued.Priority = 5
+	queued.Retry = RetryPolicy{MaxAttempts: 3, Backoff: time.Second}
+	queued.Document = []byte("^XA^XZ")
+	scheduled := New(1, 1, 0)
+	scheduled.Status = StatusScheduled
+	scheduled.NotBefore = &notBefore
+	other := New(2, 1, 0)
+	for _, j := range []*Job{&queued, &scheduled, &other} {
+		if err := repo.Store(ctx, j); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+	if queued.ID != 1 || scheduled.ID != 2 || other.ID != 3 {
+		t.Errorf("expected: ids 1, 2, 3, got: %d, %d, %d\n", queued.ID, scheduled.ID, other.ID)
+	}
+
+	stored, err := repo.Get(ctx, queued.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.Quantity != 3 || stored.Timeout != time.Second || stored.Priority != 5 ||
+		stored.Retry != queued.Retry || string(stored.Document) != "^XA^XZ" || stored.CreatedAt.IsZero() {
+		t.Errorf("expected: %+v, got: %+v\n", queued, stored)
+	}
+
+	// progress of the job is updated, the request is kept
+	update := stored
+	update.Status = StatusPrinting
+	update.Printed = 2
+	update.Attempts = 1
+	update.LastError = "connection reset"
+	update.Quantity = 10
+	update.Document = nil
+	if err := repo.Update(ctx, &update); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	stored, err = repo.Get(ctx, queued.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.Status != StatusPrinting || stored.Printed != 2 || stored.Attempts != 1 || stored.LastError != "connection reset" {
+		t.Errorf("expected: %+v, got: %+v\n", update, stored)
+	}
+	if stored.Quantity != 3 || string(stored.Document) != "^XA^XZ" {
+		t.Errorf("expected: %+v, got: %+v\n", queued, stored)
+	}
+
+	other.Status = StatusDone
+	if err := repo.Update(ctx, &other); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	unfinished, err := repo.ListUnfinished(ctx, 1)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if len(unfinished) != 2 || unfinished[0].ID != queued.ID || unfinished[1].ID != scheduled.ID {
+		t.Errorf("expected: jobs %d and %d, got: %+v\n", queued.ID, scheduled.ID, unfinished)
+	}
+	unfinished, err = repo.ListUnfinished(ctx, 2)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if len(unfinished) != 0 {
+		t.Errorf("expected: no jobs, got: %+v\n", unfinished)
+	}
+	done, err := repo.List(ctx, Filter{Status: StatusDone, Limit: DefaultListLimit})
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if len(done) != 1 || done[0].ID != other.ID {
+		t.Errorf("expected: job %d, got: %+v\n", other.ID, done)
+	}
+
+	if _, err := repo.Get(ctx, 42); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected: %v, got: %v\n", ErrNotFound, err)
+	}
+	if err := repo.Update(ctx, &Job{ID: 42}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected: %v, got: %v\n", ErrNotFound, err)
+	}
+}
@@ -0,0 +1,111 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const jobColumns = `id, printer_id, pool_id, quantity, timeout, priority, not_before, expires_at,
+	max_attempts, backoff, max_backoff, document, status, printed, attempts, last_error, created_at, updated_at`
+
+// listColumns leave the document out, lists do not need it.
+const listColumns = `id, printer_id, pool_id, quantity, timeout, priority, not_before, expires_at,
+	max_attempts, backoff, max_backoff, NULL::bytea, status, printed, attempts, last_error, created_at, updated_at`
+
+type PSQL struct {
+	pool *pgxpool.Pool
+}
+
+func NewPSQL(pool *pgxpool.Pool) (*PSQL, error) {
+	return &PSQL{pool: pool}, nil
+}
+
+func (repo *PSQL) Store(ctx context.Context, j *Job) error {
+	sql := `INSERT INTO jobs (printer_id, pool_id, quantity, timeout, priority, not_before, expires_at,
+		max_attempts, backoff, max_backoff, document, status, printed, attempts, last_error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id, created_at, updated_at`
+	row := repo.pool.QueryRow(ctx, sql,
+		j.PrinterID, j.PoolID, j.Quantity, int64(j.Timeout), j.Priority, j.NotBefore, j.ExpiresAt,
+		j.Retry.MaxAttempts, int64(j.Retry.Backoff), int64(j.Retry.MaxBackoff),
+		j.Document, j.Status, j.Printed, j.Attempts, j.LastError,
+	)
+	if err := row.Scan(&j.ID, &j.CreatedAt, &j.UpdatedAt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *PSQL) Update(ctx context.Context, j *Job) error {
+	sql := `UPDATE jobs SET printer_id = $2, status = $3, printed = $4, attempts = $5, not_before = $6, last_error = $7,
+		updated_at = now() WHERE id = $1 RETURNING updated_at`
+	row := repo.pool.QueryRow(ctx, sql, j.ID, j.PrinterID, j.Status, j.Printed, j.Attempts, j.NotBefore, j.LastError)
+	if err := row.Scan(&j.UpdatedAt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *PSQL) ListUnfinished(ctx context.Context, printerID int64) ([]Job, error) {
+	sql := "SELECT " + jobColumns + " FROM jobs WHERE printer_id = $1 AND status IN ($2, $3, $4) ORDER BY id"
+	rows, err := repo.pool.Query(ctx, sql, printerID, StatusQueued, StatusPrinting, StatusScheduled)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanJobs(rows)
+}
+
+func (repo *PSQL) List(ctx context.Context, f Filter) ([]Job, error) {
+	sql := "SELECT " + listColumns + " FROM jobs WHERE $1 = '' OR status = $1 ORDER BY id LIMIT $2 OFFSET $3"
+	rows, err := repo.pool.Query(ctx, sql, string(f.Status), f.Limit, f.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanJobs(rows)
+}
+
+func (repo *PSQL) Get(ctx context.Context, id int64) (Job, error) {
+	sql := "SELECT " + jobColumns + " FROM jobs WHERE id = $1"
+	row := repo.pool.QueryRow(ctx, sql, id)
+	j, err := scanJob(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Job{}, ErrNotFound
+		}
+		return Job{}, err
+	}
+	return j, nil
+}
+
+func scanJobs(rows pgx.Rows) ([]Job, error) {
+	jobs := []Job{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+func scanJob(row pgx.Row) (Job, error) {
+	j := Job{}
+	var timeout, backoff, maxBackoff int64
+	if err := row.Scan(
+		&j.ID, &j.PrinterID, &j.PoolID, &j.Quantity, &timeout, &j.Priority, &j.NotBefore, &j.ExpiresAt,
+		&j.Retry.MaxAttempts, &backoff, &maxBackoff, &j.Document,
+		&j.Status, &j.Printed, &j.Attempts, &j.LastError, &j.CreatedAt, &j.UpdatedAt,
+	); err != nil {
+		return Job{}, err
+	}
+	j.Timeout = time.Duration(timeout)
+	j.Retry.Backoff = time.Duration(backoff)
+	j.Retry.MaxBackoff = time.Duration(maxBackoff)
+	return j, nil
+}
@@ -0,0 +1,71 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	// DefaultListLimit is the page size of the job list if it is not given.
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+var ErrInvalidFilter = errors.New("invalid job filter")
+
+var statuses = []Status{
+	StatusQueued, StatusPrinting, StatusDone, StatusUnconfirmed, StatusFailed,
+	StatusCanceled, StatusScheduled, StatusExpired, StatusDeadLetter,
+}
+
+// Filter selects a page of jobs ordered by ID, only jobs with the status
+// are listed if it is set.
+type Filter struct {
+	Status Status
+	Limit  int
+	Offset int
+}
+
+// GetterLister lists jobs without their documents, documents are loaded
+// only by Get.
+type GetterLister interface {
+	Get(context.Context, int64) (Job, error)
+	List(context.Context, Filter) ([]Job, error)
+}
+
+type QuerySvc struct {
+	db GetterLister
+}
+
+func NewQuerySvc(db GetterLister) QuerySvc {
+	return QuerySvc{db: db}
+}
+
+func (svc QuerySvc) Get(ctx context.Context, jobID int64) (Job, error) {
+	return svc.db.Get(ctx, jobID)
+}
+
+func (svc QuerySvc) List(ctx context.Context, f Filter) ([]Job, error) {
+	if f.Limit == 0 {
+		f.Limit = DefaultListLimit
+	}
+	if f.Limit < 0 || f.Limit > MaxListLimit {
+		return nil, fmt.Errorf("%w: limit must be from 1 to %d", ErrInvalidFilter, MaxListLimit)
+	}
+	if f.Offset < 0 {
+		return nil, fmt.Errorf("%w: negative offset", ErrInvalidFilter)
+	}
+	if f.Status != "" && !slices.Contains(statuses, f.Status) {
+		return nil, fmt.Errorf("%w: unknown status %s", ErrInvalidFilter, f.Status)
+	}
+	return svc.db.List(ctx, f)
+}
+
+// ListDeadLetters lists a page of jobs that exhausted all attempts, status
+// of the filter is ignored.
+func (svc QuerySvc) ListDeadLetters(ctx context.Context, f Filter) ([]Job, error) {
+	f.Status = StatusDeadLetter
+	return svc.List(ctx, f)
+}
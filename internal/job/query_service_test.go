@@ -0,0 +1,249 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	j := New(1, 3, time.Second)
+	err = repo.Store(context.Background(), &j)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	ucs := []struct {
+		desc        string
+		jobID       int64
+		job         *Job
+		expectedErr error
+	}{
+		{
+			desc:        "happy path",
+			jobID:       j.ID,
+			job:         &j,
+			expectedErr: nil,
+		},
+		{
+			desc:        "wrong ID",
+			jobID:       -1,
+			job:         nil,
+			expectedErr: ErrNotFound,
+		},
+		{
+			desc:        "job with ID does not exist",
+			jobID:       2,
+			job:         nil,
+			expectedErr: ErrNotFound,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			svc := NewQuerySvc(repo)
+
+			got, err := svc.Get(context.Background(), us.jobID)
+			if !errors.Is(err, us.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+			if err == nil && us.job != nil {
+				if got.PrinterID != us.job.PrinterID {
+					t.Errorf("expected: %v, got: %v\n", us.job.PrinterID, got.PrinterID)
+				}
+				if got.Quantity != us.job.Quantity {
+					t.Errorf("expected: %v, got: %v\n", us.job.Quantity, got.Quantity)
+				}
+				if got.Status != StatusQueued {
+					t.Errorf("expected: %v, got: %v\n", StatusQueued, got.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	svc := NewQuerySvc(repo)
+
+	// list empty storage
+	result, err := svc.List(context.Background(), Filter{})
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if len(result) > 0 {
+		t.Fatalf("expected empty result, but got %+v\n", result)
+	}
+
+	jobs := []Job{
+		New(1, 1, 0),
+		New(2, 5, time.Second),
+	}
+	for i := range jobs {
+		err = repo.Store(context.Background(), &jobs[i])
+		if err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+	jobs[0].Status = StatusDone
+	jobs[0].Printed = 1
+	if err := repo.Update(context.Background(), &jobs[0]); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	// list all jobs in storage
+	result, err = svc.List(context.Background(), Filter{})
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if len(result) != len(jobs) {
+		t.Fatalf("expected result has same length as stored jobs: %d, but got %d\n", len(jobs), len(result))
+	}
+	if result[0].Status != StatusDone || result[0].Printed != 1 {
+		t.Errorf("expected updated job, got %+v\n", result[0])
+	}
+}
+
+func TestListFilter(t *testing.T) {
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	svc := NewQuerySvc(repo)
+
+	for i := 0; i < 5; i++ {
+		j := New(1, 1, 0)
+		j.Document = []byte("^XA^XZ")
+		if i%2 == 0 {
+			j.Status = StatusDone
+		}
+		if err := repo.Store(context.Background(), &j); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+
+	ucs := []struct {
+		desc        string
+		filter      Filter
+		expected    []int64
+		expectedErr error
+	}{
+		{
+			desc:     "default limit",
+			filter:   Filter{},
+			expected: []int64{1, 2, 3, 4, 5},
+		},
+		{
+			desc:     "page",
+			filter:   Filter{Limit: 2, Offset: 1},
+			expected: []int64{2, 3},
+		},
+		{
+			desc:     "offset past the end",
+			filter:   Filter{Limit: 2, Offset: 10},
+			expected: []int64{},
+		},
+		{
+			desc:     "status",
+			filter:   Filter{Status: StatusDone, Limit: 2},
+			expected: []int64{1, 3},
+		},
+		{
+			desc:        "unknown status",
+			filter:      Filter{Status: "lost"},
+			expectedErr: ErrInvalidFilter,
+		},
+		{
+			desc:        "limit too large",
+			filter:      Filter{Limit: MaxListLimit + 1},
+			expectedErr: ErrInvalidFilter,
+		},
+		{
+			desc:        "negative offset",
+			filter:      Filter{Offset: -1},
+			expectedErr: ErrInvalidFilter,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			result, err := svc.List(context.Background(), us.filter)
+			if !errors.Is(err, us.expectedErr) {
+				t.Fatalf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+			ids := []int64{}
+			for _, j := range result {
+				ids = append(ids, j.ID)
+				if j.Document != nil {
+					t.Errorf("expected listed job without document, got %+v\n", j)
+				}
+			}
+			if err == nil && !slices.Equal(ids, us.expected) {
+				t.Errorf("expected: %v, got: %v\n", us.expected, ids)
+			}
+		})
+	}
+}
+
+func TestListDeadLetters(t *testing.T) {
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	svc := NewQuerySvc(repo)
+
+	jobs := []Job{
+		New(1, 1, 0),
+		New(1, 1, 0),
+		New(2, 1, 0),
+	}
+	for i := range jobs {
+		jobs[i].Document = []byte("^XA^XZ")
+		if err := repo.Store(context.Background(), &jobs[i]); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+	for _, i := range []int{0, 2} {
+		jobs[i].Status = StatusDeadLetter
+		if err := repo.Update(context.Background(), &jobs[i]); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+
+	result, err := svc.ListDeadLetters(context.Background(), Filter{Status: StatusQueued})
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("expected %d dead letters, got %d\n", 2, len(result))
+	}
+	if result[0].ID != jobs[0].ID || result[1].ID != jobs[2].ID {
+		t.Errorf("expected jobs %d and %d, got %+v\n", jobs[0].ID, jobs[2].ID, result)
+	}
+	if result[0].Document != nil {
+		t.Errorf("expected listed job without document, got %+v\n", result[0])
+	}
+
+	result, err = svc.ListDeadLetters(context.Background(), Filter{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if len(result) != 1 || result[0].ID != jobs[2].ID {
+		t.Errorf("expected job %d, got %+v\n", jobs[2].ID, result)
+	}
+	if _, err := svc.ListDeadLetters(context.Background(), Filter{Offset: -1}); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("expected: %v, got: %v\n", ErrInvalidFilter, err)
+	}
+}
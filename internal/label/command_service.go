@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"time"
 
+	"zhurd/internal/job"
 	"zhurd/internal/printer"
+	"zhurd/internal/route"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -22,7 +24,12 @@ type StorerDeleter interface {
 }
 
 type Queue interface {
-	Enqueue(printerID int64, document printer.Printable, quantity int, timeout time.Duration)
+	Enqueue(context.Context, *job.Job, printer.Printable) error
+}
+
+// Router resolves enqueue request without target to a printer or a pool.
+type Router interface {
+	Resolve(context.Context, route.Request) (route.Target, error)
 }
 
 type CreateLabel struct {
@@ -31,21 +38,26 @@ type CreateLabel struct {
 }
 
 type CreateTemplate struct {
-	LabelID int64
-	Type    string `json:"type" validate:"required"`
-	Body    []byte `json:"body" validate:"required"`
+	LabelID  int64
+	Type     string   `json:"type" validate:"required"`
+	Body     []byte   `json:"body" validate:"required"`
+	DPI      int      `json:"dpi" validate:"min=0"`
+	Width    int      `json:"width" validate:"min=0"`
+	Requires []string `json:"requires" validate:"dive,oneof=cutter rfid"`
 }
 
 type CommandSvc struct {
 	db       StorerDeleter
 	queue    Queue
+	router   Router
 	validate *validator.Validate
 }
 
-func NewCommandSvc(db StorerDeleter, queue Queue) CommandSvc {
+func NewCommandSvc(db StorerDeleter, queue Queue, router Router) CommandSvc {
 	return CommandSvc{
 		db:       db,
 		queue:    queue,
+		router:   router,
 		validate: validator.New(validator.WithRequiredStructEnabled()),
 	}
 }
@@ -81,6 +93,9 @@ func (svc CommandSvc) CreateTemplate(ctx context.Context, ct CreateTemplate) (Te
 	if err != nil {
 		return Template{}, fmt.Errorf("%w: %w", ValidationError, err)
 	}
+	t.DPI = ct.DPI
+	t.Width = ct.Width
+	t.Requires = ct.Requires
 
 	if err := svc.db.StoreTemplate(ctx, &t); err != nil {
 		return Template{}, err
@@ -93,15 +108,55 @@ func (svc CommandSvc) DeleteTemplate(ctx context.Context, labelID, templateID in
 	return svc.db.DeleteTemplate(ctx, labelID, templateID)
 }
 
-func (svc CommandSvc) Enqueue(ctx context.Context, labelID int64, enqueueLabel EnqueueLabel) error {
+func (svc CommandSvc) Enqueue(ctx context.Context, labelID int64, enqueueLabel EnqueueLabel) (int64, error) {
+	if enqueueLabel.PrinterID != 0 && enqueueLabel.PoolID != 0 {
+		return 0, fmt.Errorf("%w: either printer_id or pool_id must be set", ValidationError)
+	}
+	if enqueueLabel.PrinterID == 0 && enqueueLabel.PoolID == 0 && enqueueLabel.Station == "" && enqueueLabel.Zone == "" {
+		return 0, fmt.Errorf("%w: printer_id, pool_id, station or zone must be set", ValidationError)
+	}
+	if enqueueLabel.ExpiresAt != nil {
+		if !enqueueLabel.ExpiresAt.After(time.Now()) {
+			return 0, fmt.Errorf("%w: expires_at is in the past", ValidationError)
+		}
+		if enqueueLabel.NotBefore != nil && !enqueueLabel.ExpiresAt.After(*enqueueLabel.NotBefore) {
+			return 0, fmt.Errorf("%w: expires_at must be after not_before", ValidationError)
+		}
+	}
+	if enqueueLabel.Retry != nil && enqueueLabel.Retry.MaxAttempts < 1 {
+		return 0, fmt.Errorf("%w: retry max_attempts must be positive", ValidationError)
+	}
 	label, err := svc.db.GetLabel(ctx, labelID)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	if enqueueLabel.PrinterID == 0 && enqueueLabel.PoolID == 0 {
+		target, err := svc.router.Resolve(ctx, route.Request{
+			Station: enqueueLabel.Station,
+			Zone:    enqueueLabel.Zone,
+			LabelID: labelID,
+		})
+		if err != nil {
+			return 0, err
+		}
+		enqueueLabel.PrinterID, enqueueLabel.PoolID = target.PrinterID, target.PoolID
 	}
 	label.placeholders = make(map[string]string, len(enqueueLabel.Placeholders))
 	for _, ph := range enqueueLabel.Placeholders {
 		label.placeholders[ph.Name] = ph.Value
 	}
-	svc.queue.Enqueue(enqueueLabel.PrinterID, label, enqueueLabel.Quantity, enqueueLabel.Timeout)
-	return nil
+	j := job.New(enqueueLabel.PrinterID, enqueueLabel.Quantity, enqueueLabel.Timeout)
+	j.Priority = enqueueLabel.Priority
+	j.NotBefore = enqueueLabel.NotBefore
+	j.ExpiresAt = enqueueLabel.ExpiresAt
+	if enqueueLabel.PoolID != 0 {
+		j.PoolID = &enqueueLabel.PoolID
+	}
+	if enqueueLabel.Retry != nil {
+		j.Retry = *enqueueLabel.Retry
+	}
+	if err := svc.queue.Enqueue(ctx, &j, label); err != nil {
+		return 0, err
+	}
+	return j.ID, nil
 }
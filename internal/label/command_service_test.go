@@ -6,15 +6,31 @@ import (
 	"slices"
 	"testing"
 	"time"
+	"zhurd/internal/job"
 	"zhurd/internal/printer"
+	"zhurd/internal/route"
 )
 
 type TestQueue struct {
 	enqueued int
+	last     job.Job
 }
 
-func (q *TestQueue) Enqueue(printerID int64, document printer.Printable, quantity int, timeout time.Duration) {
+func (q *TestQueue) Enqueue(ctx context.Context, j *job.Job, document printer.Printable) error {
 	q.enqueued++
+	j.ID = int64(q.enqueued)
+	q.last = *j
+	return nil
+}
+
+// TestRouter routes station A to pool 7.
+type TestRouter struct{}
+
+func (TestRouter) Resolve(ctx context.Context, req route.Request) (route.Target, error) {
+	if req.Station == "A" {
+		return route.Target{PoolID: 7}, nil
+	}
+	return route.Target{}, route.ErrNoRoute
 }
 
 func TestRegisterLabel(t *testing.T) {
@@ -49,7 +65,7 @@ func TestRegisterLabel(t *testing.T) {
 				t.Fatalf("got error: %s\n", err)
 			}
 			q := &TestQueue{}
-			svc := NewCommandSvc(repo, q)
+			svc := NewCommandSvc(repo, q, nil)
 
 			l, err := svc.CreateLabel(context.Background(), us.cl)
 			if !errors.Is(err, us.expectedErr) {
@@ -73,7 +89,7 @@ func TestDeleteLabel(t *testing.T) {
 		t.Fatalf("got error: %s\n", err)
 	}
 	q := &TestQueue{}
-	svc := NewCommandSvc(repo, q)
+	svc := NewCommandSvc(repo, q, nil)
 	label := &Label{
 		Name:    "new label",
 		Comment: "test label",
@@ -139,7 +155,7 @@ func TestRegisterTemplate(t *testing.T) {
 				t.Fatalf("got error: %s\n", err)
 			}
 			q := &TestQueue{}
-			svc := NewCommandSvc(repo, q)
+			svc := NewCommandSvc(repo, q, nil)
 			label := &Label{
 				Name:    "new label",
 				Comment: "test label",
@@ -182,7 +198,7 @@ func TestDeleteTemplate(t *testing.T) {
 	}
 	repo.StoreLabel(context.Background(), label)
 	q := &TestQueue{}
-	svc := NewCommandSvc(repo, q)
+	svc := NewCommandSvc(repo, q, nil)
 	template := &Template{
 		LabelID: label.ID,
 		Type:    "ZPL",
@@ -219,7 +235,7 @@ func TestEnqueue(t *testing.T) {
 	}
 	repo.StoreLabel(context.Background(), label)
 	q := &TestQueue{}
-	svc := NewCommandSvc(repo, q)
+	svc := NewCommandSvc(repo, q, nil)
 	template := &Template{
 		LabelID: label.ID,
 		Type:    "ZPL",
@@ -241,8 +257,163 @@ func TestEnqueue(t *testing.T) {
 		Placeholders: []Placeholder{},
 	}
 
-	svc.Enqueue(context.Background(), label.ID, enc)
+	jobID, err := svc.Enqueue(context.Background(), label.ID, enc)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
 	if q.enqueued != 1 {
 		t.Fatalf("expect enqueued tasks: %d, got: %d\n", 1, q.enqueued)
 	}
+	if jobID != 1 {
+		t.Errorf("expected job ID: %d, got: %d\n", 1, jobID)
+	}
+}
+
+func TestEnqueueSchedule(t *testing.T) {
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	label := &Label{
+		Name: "label",
+	}
+	repo.StoreLabel(context.Background(), label)
+
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	soon := now.Add(time.Minute)
+	later := now.Add(time.Hour)
+	ucs := []struct {
+		desc        string
+		notBefore   *time.Time
+		expiresAt   *time.Time
+		expectedErr error
+	}{
+		{
+			desc:        "no schedule",
+			expectedErr: nil,
+		},
+		{
+			desc:        "scheduled with deadline",
+			notBefore:   &soon,
+			expiresAt:   &later,
+			expectedErr: nil,
+		},
+		{
+			desc:        "already expired",
+			expiresAt:   &past,
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "expires before it is due",
+			notBefore:   &later,
+			expiresAt:   &soon,
+			expectedErr: ValidationError,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			q := &TestQueue{}
+			svc := NewCommandSvc(repo, q, nil)
+			enc := EnqueueLabel{
+				PrinterID: 1,
+				Quantity:  1,
+				NotBefore: us.notBefore,
+				ExpiresAt: us.expiresAt,
+			}
+			_, err := svc.Enqueue(context.Background(), label.ID, enc)
+			if !errors.Is(err, us.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestEnqueueTarget(t *testing.T) {
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	label := &Label{
+		Name: "label",
+	}
+	repo.StoreLabel(context.Background(), label)
+
+	ucs := []struct {
+		desc         string
+		printerID    int64
+		poolID       int64
+		station      string
+		expectedPool int64
+		expectedErr  error
+	}{
+		{
+			desc:        "printer",
+			printerID:   1,
+			expectedErr: nil,
+		},
+		{
+			desc:         "pool",
+			poolID:       1,
+			expectedPool: 1,
+			expectedErr:  nil,
+		},
+		{
+			desc:        "printer and pool",
+			printerID:   1,
+			poolID:      1,
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "no target",
+			expectedErr: ValidationError,
+		},
+		{
+			desc:         "routed",
+			station:      "A",
+			expectedPool: 7,
+			expectedErr:  nil,
+		},
+		{
+			desc:        "no route",
+			station:     "B",
+			expectedErr: route.ErrNoRoute,
+		},
+		{
+			desc:         "target is preferred to route",
+			printerID:    1,
+			station:      "A",
+			expectedPool: 0,
+			expectedErr:  nil,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			q := &TestQueue{}
+			svc := NewCommandSvc(repo, q, TestRouter{})
+			enc := EnqueueLabel{
+				PrinterID: us.printerID,
+				PoolID:    us.poolID,
+				Station:   us.station,
+				Quantity:  1,
+			}
+			_, err := svc.Enqueue(context.Background(), label.ID, enc)
+			if !errors.Is(err, us.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if us.expectedPool != 0 && (q.last.PoolID == nil || *q.last.PoolID != us.expectedPool) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedPool, q.last.PoolID)
+			}
+			if us.expectedPool == 0 && q.last.PoolID != nil {
+				t.Errorf("expected: %v, got: %v\n", nil, *q.last.PoolID)
+			}
+		})
+	}
 }
@@ -3,22 +3,64 @@ package label
 import (
 	"fmt"
 	"time"
+
+	"zhurd/internal/job"
+	"zhurd/internal/printer"
 )
 
 type Label struct {
 	ID           int64  `json:"id"`
 	Name         string `json:"name"`
 	Comment      string `json:"comment"`
-	templates    map[string]Template
+	templates    []Template
 	placeholders map[string]string
 }
 
 func (l Label) Print(pType string) ([]byte, error) {
-	tplt, ok := l.templates[pType]
-	if !ok {
-		return nil, fmt.Errorf("label has no template with type: %s", pType)
+	return l.PrintFor(pType, nil)
+}
+
+// PrintFor renders the template of the printer type that fits capabilities
+// of the printer, template of the printer language is used if there is no
+// template of the type. Template designed for the printer resolution is
+// preferred to the one that fits any.
+func (l Label) PrintFor(pType string, c *printer.Capabilities) ([]byte, error) {
+	candidates := l.templatesOf(pType)
+	if len(candidates) == 0 && c != nil && c.Language != "" {
+		candidates = l.templatesOf(c.Language)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: %s", MissingTemplateError, pType)
+	}
+
+	var (
+		best   *Template
+		misfit error
+	)
+	for i := range candidates {
+		t := &candidates[i]
+		if err := t.Fits(c); err != nil {
+			misfit = err
+			continue
+		}
+		if best == nil || (c != nil && c.DPI != 0 && best.DPI != c.DPI && t.DPI == c.DPI) {
+			best = t
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w: %w", IncompatibleTemplateError, misfit)
+	}
+	return best.Print(l.placeholders)
+}
+
+func (l Label) templatesOf(pType string) []Template {
+	var templates []Template
+	for _, t := range l.templates {
+		if t.Type == pType {
+			templates = append(templates, t)
+		}
 	}
-	return tplt.Print(l.placeholders)
+	return templates
 }
 
 type Placeholder struct {
@@ -26,9 +68,18 @@ type Placeholder struct {
 	Value string `json:"value"`
 }
 
+// EnqueueLabel targets either a printer or a pool of printers, request
+// without target is routed by station and zone.
 type EnqueueLabel struct {
-	PrinterID    int64         `json:"printer_id"`
-	Quantity     int           `json:"quantity"`
-	Timeout      time.Duration `json:"timeout"`
-	Placeholders []Placeholder `json:"placeholders"`
+	PrinterID    int64            `json:"printer_id"`
+	PoolID       int64            `json:"pool_id"`
+	Station      string           `json:"station"`
+	Zone         string           `json:"zone"`
+	Quantity     int              `json:"quantity"`
+	Timeout      time.Duration    `json:"timeout"`
+	Priority     int              `json:"priority"`
+	NotBefore    *time.Time       `json:"not_before"`
+	ExpiresAt    *time.Time       `json:"expires_at"`
+	Retry        *job.RetryPolicy `json:"retry"`
+	Placeholders []Placeholder    `json:"placeholders"`
 }
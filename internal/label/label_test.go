@@ -0,0 +1,80 @@
+package label
+
+import (
+	"errors"
+	"testing"
+
+	"zhurd/internal/printer"
+)
+
+func TestPrintFor(t *testing.T) {
+	l := Label{
+		templates: []Template{
+			{Type: "ZPL", Body: []byte("any")},
+			{Type: "ZPL", Body: []byte("300"), DPI: 300},
+			{Type: "ZPL", Body: []byte("wide"), DPI: 203, Width: 1200},
+			{Type: "EPL", Body: []byte("cut"), Requires: []string{printer.OptionCutter}},
+		},
+	}
+	ucs := []struct {
+		desc          string
+		pType         string
+		capabilities  *printer.Capabilities
+		expected      string
+		expectedError error
+	}{
+		{
+			desc:     "unknown capabilities",
+			pType:    "ZPL",
+			expected: "any",
+		},
+		{
+			desc:         "resolution is preferred",
+			pType:        "ZPL",
+			capabilities: &printer.Capabilities{DPI: 300},
+			expected:     "300",
+		},
+		{
+			desc:         "too wide is skipped",
+			pType:        "ZPL",
+			capabilities: &printer.Capabilities{DPI: 203, PrintWidth: 832},
+			expected:     "any",
+		},
+		{
+			desc:         "template of the language",
+			pType:        "ZD420",
+			capabilities: &printer.Capabilities{Language: "ZPL", DPI: 300},
+			expected:     "300",
+		},
+		{
+			desc:          "missing option",
+			pType:         "EPL",
+			capabilities:  &printer.Capabilities{},
+			expectedError: IncompatibleTemplateError,
+		},
+		{
+			desc:         "installed option",
+			pType:        "EPL",
+			capabilities: &printer.Capabilities{Options: []string{printer.OptionCutter}},
+			expected:     "cut",
+		},
+		{
+			desc:          "missing type",
+			pType:         "TSPL",
+			expectedError: MissingTemplateError,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			result, err := l.PrintFor(us.pType, us.capabilities)
+			if !errors.Is(err, us.expectedError) {
+				t.Fatalf("expected: %v, got: %v\n", us.expectedError, err)
+			}
+			if string(result) != us.expected {
+				t.Errorf("expected: %s, got: %s\n", us.expected, result)
+			}
+		})
+	}
+}
@@ -79,10 +79,7 @@ func (m *Memory) GetLabel(ctx context.Context, id int64) (Label, error) {
 	if err != nil {
 		return Label{}, err
 	}
-	l.templates = make(map[string]Template, len(tmplts))
-	for _, tmplt := range tmplts {
-		l.templates[tmplt.Type] = tmplt
-	}
+	l.templates = tmplts
 	return l, nil
 }
 
@@ -61,10 +61,7 @@ func (repo *PSQL) GetLabel(ctx context.Context, id int64) (Label, error) {
 	if err != nil {
 		return Label{}, err
 	}
-	l.templates = make(map[string]Template, len(tmplts))
-	for _, tmplt := range tmplts {
-		l.templates[tmplt.Type] = tmplt
-	}
+	l.templates = tmplts
 
 	return l, nil
 }
@@ -79,8 +76,11 @@ func (repo *PSQL) DeleteLabel(ctx context.Context, id int64) error {
 }
 
 func (repo *PSQL) StoreTemplate(ctx context.Context, t *Template) error {
-	sql := "INSERT INTO templates (label_id, type, body) VALUES ($1, $2, $3) RETURNING id"
-	row := repo.pool.QueryRow(ctx, sql, t.LabelID, t.Type, t.Body)
+	if t.Requires == nil {
+		t.Requires = []string{}
+	}
+	sql := "INSERT INTO templates (label_id, type, body, dpi, width, requires) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+	row := repo.pool.QueryRow(ctx, sql, t.LabelID, t.Type, t.Body, t.DPI, t.Width, t.Requires)
 	if err := row.Scan(&t.ID); err != nil {
 		return err
 	}
@@ -88,7 +88,7 @@ func (repo *PSQL) StoreTemplate(ctx context.Context, t *Template) error {
 }
 
 func (repo *PSQL) ListTemplates(ctx context.Context, labelID int64) ([]Template, error) {
-	sql := "SELECT id, label_id, type, body FROM templates WHERE label_id = $1"
+	sql := "SELECT id, label_id, type, body, dpi, width, requires FROM templates WHERE label_id = $1"
 	rows, err := repo.pool.Query(ctx, sql, labelID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -100,7 +100,7 @@ func (repo *PSQL) ListTemplates(ctx context.Context, labelID int64) ([]Template,
 	templates := []Template{}
 	for rows.Next() {
 		t := Template{}
-		if err := rows.Scan(&t.ID, &t.LabelID, &t.Type, &t.Body); err != nil {
+		if err := rows.Scan(&t.ID, &t.LabelID, &t.Type, &t.Body, &t.DPI, &t.Width, &t.Requires); err != nil {
 			return nil, err
 		}
 		templates = append(templates, t)
@@ -109,10 +109,10 @@ func (repo *PSQL) ListTemplates(ctx context.Context, labelID int64) ([]Template,
 }
 
 func (repo *PSQL) GetTemplate(ctx context.Context, labelID, templateID int64) (Template, error) {
-	sql := "SELECT id, label_id, type, body FROM templates WHERE id = $1 AND label_id = $2"
+	sql := "SELECT id, label_id, type, body, dpi, width, requires FROM templates WHERE id = $1 AND label_id = $2"
 	row := repo.pool.QueryRow(ctx, sql, templateID, labelID)
 	t := Template{}
-	if err := row.Scan(&t.ID, &t.LabelID, &t.Type, &t.Body); err != nil {
+	if err := row.Scan(&t.ID, &t.LabelID, &t.Type, &t.Body, &t.DPI, &t.Width, &t.Requires); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return Template{}, ErrNotFound
 		}
@@ -3,24 +3,37 @@ package label
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"unicode"
 	"unicode/utf8"
+
+	"zhurd/internal/printer"
 )
 
 var (
-	MissingPlaceholderError = errors.New("missing placeholder")
-	DecodingError           = errors.New("template body decoding error")
+	MissingPlaceholderError   = errors.New("missing placeholder")
+	MissingTemplateError      = errors.New("label has no template with type")
+	IncompatibleTemplateError = errors.New("label has no template compatible with printer")
+	DecodingError             = errors.New("template body decoding error")
 )
 
 const (
 	separator = "^_"
 )
 
+// Template is the label rendered for the printer type, optional
+// requirements restrict the printers it is used for.
 type Template struct {
 	ID      int64  `json:"id"`
 	LabelID int64  `json:"label_id"`
 	Type    string `json:"type"`
 	Body    []byte `json:"body"`
+	// DPI is the resolution the template is designed for, 0 fits any.
+	DPI int `json:"dpi"`
+	// Width is the width of the label in dots, 0 fits any.
+	Width int `json:"width"`
+	// Requires are options the printer must have, e.g. cutter.
+	Requires []string `json:"requires"`
 }
 
 func NewTemplate(labelID int64, pType string, body []byte) (Template, error) {
@@ -35,6 +48,26 @@ func NewTemplate(labelID int64, pType string, body []byte) (Template, error) {
 	}, nil
 }
 
+// Fits checks the template against capabilities of the printer, unknown
+// capabilities fit any template.
+func (t Template) Fits(c *printer.Capabilities) error {
+	if c == nil {
+		return nil
+	}
+	if t.DPI != 0 && c.DPI != 0 && t.DPI != c.DPI {
+		return fmt.Errorf("template is designed for %d dpi, printer has %d dpi", t.DPI, c.DPI)
+	}
+	if t.Width != 0 && c.PrintWidth != 0 && t.Width > c.PrintWidth {
+		return fmt.Errorf("template is %d dots wide, printer prints %d dots", t.Width, c.PrintWidth)
+	}
+	for _, option := range t.Requires {
+		if !c.Has(option) {
+			return fmt.Errorf("template requires %s", option)
+		}
+	}
+	return nil
+}
+
 func (t Template) Print(placeholders map[string]string) ([]byte, error) {
 	output := bytes.NewBuffer([]byte{})
 	parts := bytes.Split(t.Body, []byte(separator))
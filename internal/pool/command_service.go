@@ -0,0 +1,116 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"zhurd/internal/printer"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	ValidationError = errors.New("Validation error")
+	// ErrUnknownPrinter is returned if a member of the pool is not registered.
+	ErrUnknownPrinter = errors.New("unknown printer")
+)
+
+type StorerDeleter interface {
+	Store(context.Context, *Pool) error
+	Update(context.Context, *Pool) error
+	Delete(context.Context, int64) error
+}
+
+type PrinterGetter interface {
+	Get(context.Context, int64) (printer.Printer, error)
+}
+
+type Queue interface {
+	SetPools(ctx context.Context, pools ...Pool) error
+	DeletePool(ctx context.Context, id int64) error
+}
+
+type CreatePool struct {
+	Name       string  `json:"name" validate:"required"`
+	Comment    string  `json:"comment"`
+	PrinterIDs []int64 `json:"printer_ids" validate:"required,min=1,unique,dive,min=1"`
+}
+
+type CommandSvc struct {
+	db       StorerDeleter
+	printers PrinterGetter
+	queue    Queue
+	validate *validator.Validate
+}
+
+func NewCommandSvc(db StorerDeleter, printers PrinterGetter, queue Queue) CommandSvc {
+	return CommandSvc{
+		db:       db,
+		printers: printers,
+		queue:    queue,
+		validate: validator.New(validator.WithRequiredStructEnabled()),
+	}
+}
+
+func (svc CommandSvc) Create(ctx context.Context, cp CreatePool) (Pool, error) {
+	p, err := svc.newPool(ctx, cp)
+	if err != nil {
+		return Pool{}, err
+	}
+
+	if err := svc.db.Store(ctx, &p); err != nil {
+		return Pool{}, err
+	}
+
+	if err := svc.queue.SetPools(ctx, p); err != nil {
+		return Pool{}, err
+	}
+	return p, nil
+}
+
+// Replace replaces name and members of the pool, jobs that are already
+// dispatched to the removed members stay there.
+func (svc CommandSvc) Replace(ctx context.Context, poolID int64, cp CreatePool) (Pool, error) {
+	p, err := svc.newPool(ctx, cp)
+	if err != nil {
+		return Pool{}, err
+	}
+	p.ID = poolID
+
+	if err := svc.db.Update(ctx, &p); err != nil {
+		return Pool{}, err
+	}
+
+	if err := svc.queue.SetPools(ctx, p); err != nil {
+		return Pool{}, err
+	}
+	return p, nil
+}
+
+func (svc CommandSvc) Delete(ctx context.Context, poolID int64) error {
+	if err := svc.db.Delete(ctx, poolID); err != nil {
+		return err
+	}
+	return svc.queue.DeletePool(ctx, poolID)
+}
+
+// newPool validates the pool, all members must be registered printers.
+func (svc CommandSvc) newPool(ctx context.Context, cp CreatePool) (Pool, error) {
+	if err := svc.validate.Struct(cp); err != nil {
+		return Pool{}, fmt.Errorf("%w: %w", ValidationError, err)
+	}
+	for _, printerID := range cp.PrinterIDs {
+		if _, err := svc.printers.Get(ctx, printerID); err != nil {
+			if errors.Is(err, printer.ErrNotFound) {
+				return Pool{}, fmt.Errorf("%w: %d", ErrUnknownPrinter, printerID)
+			}
+			return Pool{}, err
+		}
+	}
+	return Pool{
+		Name:       cp.Name,
+		Comment:    cp.Comment,
+		PrinterIDs: cp.PrinterIDs,
+	}, nil
+}
@@ -0,0 +1,135 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"zhurd/internal/printer"
+)
+
+type TestQueue struct {
+	set     int
+	deleted int
+}
+
+func (q *TestQueue) SetPools(ctx context.Context, pools ...Pool) error {
+	q.set += len(pools)
+	return nil
+}
+
+func (q *TestQueue) DeletePool(ctx context.Context, id int64) error {
+	q.deleted++
+	return nil
+}
+
+func TestCreate(t *testing.T) {
+	printers, err := printer.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	for range 2 {
+		if err := printers.Store(context.Background(), &printer.Printer{Addr: "127.0.0.1:9100", Type: "ZPL"}); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+
+	ucs := []struct {
+		desc        string
+		cp          CreatePool
+		expectedErr error
+	}{
+		{
+			desc:        "happy path",
+			cp:          CreatePool{Name: "packing-line-2", PrinterIDs: []int64{1, 2}},
+			expectedErr: nil,
+		},
+		{
+			desc:        "taken name",
+			cp:          CreatePool{Name: "packing-line-2", PrinterIDs: []int64{1}},
+			expectedErr: ErrNameTaken,
+		},
+		{
+			desc:        "no name",
+			cp:          CreatePool{PrinterIDs: []int64{1}},
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "no members",
+			cp:          CreatePool{Name: "empty"},
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "duplicated member",
+			cp:          CreatePool{Name: "duplicated", PrinterIDs: []int64{1, 1}},
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "unknown printer",
+			cp:          CreatePool{Name: "unknown", PrinterIDs: []int64{1, 3}},
+			expectedErr: ErrUnknownPrinter,
+		},
+	}
+
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	q := &TestQueue{}
+	svc := NewCommandSvc(repo, printers, q)
+	for _, uc := range ucs {
+		t.Run(uc.desc, func(t *testing.T) {
+			_, err := svc.Create(context.Background(), uc.cp)
+			if !errors.Is(err, uc.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", uc.expectedErr, err)
+			}
+		})
+	}
+	if q.set != 1 {
+		t.Errorf("expected: %v, got: %v\n", 1, q.set)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	printers, err := printer.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	for range 3 {
+		if err := printers.Store(context.Background(), &printer.Printer{Addr: "127.0.0.1:9100", Type: "ZPL"}); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	q := &TestQueue{}
+	svc := NewCommandSvc(repo, printers, q)
+	p, err := svc.Create(context.Background(), CreatePool{Name: "line", PrinterIDs: []int64{1, 2}})
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	if _, err := svc.Replace(context.Background(), p.ID, CreatePool{Name: "line", PrinterIDs: []int64{2, 3}}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	got, err := repo.Get(context.Background(), p.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if got.Has(1) || !got.Has(2) || !got.Has(3) {
+		t.Errorf("expected: %v, got: %v\n", []int64{2, 3}, got.PrinterIDs)
+	}
+
+	if _, err := svc.Replace(context.Background(), 42, CreatePool{Name: "other", PrinterIDs: []int64{1}}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected: %v, got: %v\n", ErrNotFound, err)
+	}
+
+	if err := svc.Delete(context.Background(), p.ID); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if q.set != 2 || q.deleted != 1 {
+		t.Errorf("expected: %v set and %v deleted, got: %v and %v\n", 2, 1, q.set, q.deleted)
+	}
+}
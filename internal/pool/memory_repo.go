@@ -0,0 +1,130 @@
+package pool
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+)
+
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrNameTaken = errors.New("pool name is taken")
+)
+
+type Memory struct {
+	m      map[int64][]byte
+	nextID int64
+	mu     sync.RWMutex
+}
+
+func NewMemory() (*Memory, error) {
+	return &Memory{
+		m:      make(map[int64][]byte),
+		nextID: 1,
+		mu:     sync.RWMutex{},
+	}, nil
+}
+
+func (m *Memory) Store(ctx context.Context, p *Pool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkName(*p); err != nil {
+		return err
+	}
+	if p.ID == 0 {
+		if _, ok := m.m[m.nextID]; ok {
+			panic("could not generate unique ID for pool")
+		}
+		p.ID = m.nextID
+		m.nextID += 1
+	}
+	return m.put(*p)
+}
+
+func (m *Memory) Update(ctx context.Context, p *Pool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.m[p.ID]; !ok {
+		return ErrNotFound
+	}
+	if err := m.checkName(*p); err != nil {
+		return err
+	}
+	return m.put(*p)
+}
+
+func (m *Memory) Get(ctx context.Context, id int64) (Pool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.m[id]
+	if !ok {
+		return Pool{}, ErrNotFound
+	}
+	var p Pool
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Pool{}, err
+	}
+	return p, nil
+}
+
+func (m *Memory) List(ctx context.Context) ([]Pool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	pools, err := m.list()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(pools, func(a, b Pool) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return pools, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.m[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.m, id)
+	return nil
+}
+
+func (m *Memory) put(p Pool) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	m.m[p.ID] = data
+	return nil
+}
+
+func (m *Memory) list() ([]Pool, error) {
+	pools := make([]Pool, 0, len(m.m))
+	for _, val := range m.m {
+		var p Pool
+		if err := json.Unmarshal(val, &p); err != nil {
+			return nil, err
+		}
+		pools = append(pools, p)
+	}
+	return pools, nil
+}
+
+// checkName returns ErrNameTaken if another pool has the same name, m.mu
+// must be held.
+func (m *Memory) checkName(p Pool) error {
+	pools, err := m.list()
+	if err != nil {
+		return err
+	}
+	for _, other := range pools {
+		if other.ID != p.ID && other.Name == p.Name {
+			return ErrNameTaken
+		}
+	}
+	return nil
+}
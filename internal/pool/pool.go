@@ -0,0 +1,16 @@
+package pool
+
+import "slices"
+
+// Pool is a named group of printers that is used as a target of jobs,
+// jobs go to the least loaded healthy member.
+type Pool struct {
+	ID         int64   `json:"id"`
+	Name       string  `json:"name"`
+	Comment    string  `json:"comment"`
+	PrinterIDs []int64 `json:"printer_ids"`
+}
+
+func (p Pool) Has(printerID int64) bool {
+	return slices.Contains(p.PrinterIDs, printerID)
+}
@@ -0,0 +1,165 @@
+package pool
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// codes of postgres errors
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+type PSQL struct {
+	pool *pgxpool.Pool
+}
+
+func NewPSQL(pool *pgxpool.Pool) (*PSQL, error) {
+	return &PSQL{pool: pool}, nil
+}
+
+func (repo *PSQL) Store(ctx context.Context, p *Pool) error {
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := "INSERT INTO pools (name, comment) VALUES ($1, $2) RETURNING id"
+	if err := tx.QueryRow(ctx, sql, p.Name, p.Comment).Scan(&p.ID); err != nil {
+		return mapError(err)
+	}
+	if err := storeMembers(ctx, tx, *p); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (repo *PSQL) Update(ctx context.Context, p *Pool) error {
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := "UPDATE pools SET name = $2, comment = $3 WHERE id = $1"
+	tag, err := tx.Exec(ctx, sql, p.ID, p.Name, p.Comment)
+	if err != nil {
+		return mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM pool_printers WHERE pool_id = $1", p.ID); err != nil {
+		return err
+	}
+	if err := storeMembers(ctx, tx, *p); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// storeMembers inserts members of the pool keeping their order.
+func storeMembers(ctx context.Context, tx pgx.Tx, p Pool) error {
+	sql := "INSERT INTO pool_printers (pool_id, printer_id, position) VALUES ($1, $2, $3)"
+	for i, printerID := range p.PrinterIDs {
+		if _, err := tx.Exec(ctx, sql, p.ID, printerID, i); err != nil {
+			return mapError(err)
+		}
+	}
+	return nil
+}
+
+func (repo *PSQL) Get(ctx context.Context, id int64) (Pool, error) {
+	sql := "SELECT id, name, comment FROM pools WHERE id = $1"
+	p := Pool{}
+	if err := repo.pool.QueryRow(ctx, sql, id).Scan(&p.ID, &p.Name, &p.Comment); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Pool{}, ErrNotFound
+		}
+		return Pool{}, err
+	}
+	members, err := repo.listMembers(ctx, &id)
+	if err != nil {
+		return Pool{}, err
+	}
+	p.PrinterIDs = members[id]
+	return p, nil
+}
+
+func (repo *PSQL) List(ctx context.Context) ([]Pool, error) {
+	sql := "SELECT id, name, comment FROM pools ORDER BY id"
+	rows, err := repo.pool.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	pools := []Pool{}
+	for rows.Next() {
+		p := Pool{}
+		if err := rows.Scan(&p.ID, &p.Name, &p.Comment); err != nil {
+			return nil, err
+		}
+		pools = append(pools, p)
+	}
+	rows.Close()
+
+	members, err := repo.listMembers(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pools {
+		pools[i].PrinterIDs = members[pools[i].ID]
+	}
+	return pools, nil
+}
+
+// listMembers returns printer IDs by pool ID, members of all pools are
+// returned if poolID is nil.
+func (repo *PSQL) listMembers(ctx context.Context, poolID *int64) (map[int64][]int64, error) {
+	sql := "SELECT pool_id, printer_id FROM pool_printers WHERE $1::BIGINT IS NULL OR pool_id = $1 ORDER BY pool_id, position"
+	rows, err := repo.pool.Query(ctx, sql, poolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	members := map[int64][]int64{}
+	for rows.Next() {
+		var id, printerID int64
+		if err := rows.Scan(&id, &printerID); err != nil {
+			return nil, err
+		}
+		members[id] = append(members[id], printerID)
+	}
+	return members, rows.Err()
+}
+
+func (repo *PSQL) Delete(ctx context.Context, id int64) error {
+	sql := "DELETE FROM pools WHERE id = $1"
+	tag, err := repo.pool.Exec(ctx, sql, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func mapError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation:
+			return ErrNameTaken
+		case foreignKeyViolation:
+			return ErrUnknownPrinter
+		}
+	}
+	return err
+}
@@ -0,0 +1,24 @@
+package pool
+
+import "context"
+
+type GetterLister interface {
+	Get(context.Context, int64) (Pool, error)
+	List(context.Context) ([]Pool, error)
+}
+
+type QuerySvc struct {
+	db GetterLister
+}
+
+func NewQuerySvc(db GetterLister) QuerySvc {
+	return QuerySvc{db: db}
+}
+
+func (svc QuerySvc) Get(ctx context.Context, poolID int64) (Pool, error) {
+	return svc.db.Get(ctx, poolID)
+}
+
+func (svc QuerySvc) List(ctx context.Context) ([]Pool, error) {
+	return svc.db.List(ctx)
+}
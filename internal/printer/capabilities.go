@@ -0,0 +1,131 @@
+package printer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Languages of printers besides ZPL.
+const (
+	TypeEPL  = "EPL"
+	TypeTSPL = "TSPL"
+)
+
+// Installed options of the printer.
+const (
+	OptionCutter = "cutter"
+	OptionRFID   = "rfid"
+)
+
+// Capabilities describe what the printer can print, they are detected from
+// ~HI and SGD of ZPL printer or set by hand.
+type Capabilities struct {
+	Language string `json:"language" validate:"omitempty,oneof=ZPL EPL TSPL"`
+	DPI      int    `json:"dpi" validate:"min=0"`
+	// PrintWidth is the maximum print width in dots.
+	PrintWidth int `json:"print_width" validate:"min=0"`
+	// MediaType is gap, mark or continuous.
+	MediaType  string     `json:"media_type"`
+	MemoryKB   int        `json:"memory_kb" validate:"min=0"`
+	Options    []string   `json:"options"`
+	Model      string     `json:"model"`
+	Firmware   string     `json:"firmware"`
+	DetectedAt *time.Time `json:"detected_at,omitempty"`
+}
+
+// Has reports whether the option is installed.
+func (c Capabilities) Has(option string) bool {
+	return slices.Contains(c.Options, option)
+}
+
+// identificationOptions maps option codes of ~HI to installed options.
+var identificationOptions = map[string]string{
+	"C": OptionCutter,
+}
+
+// QueryCapabilities detects capabilities of ZPL printer, ~HI is required,
+// SGD variables are read if the printer supports them.
+func (p *Printer) QueryCapabilities(ctx context.Context) (Capabilities, error) {
+	id, err := p.QueryIdentification(ctx)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	now := time.Now()
+	c := Capabilities{
+		Language:   TypeZPL,
+		DPI:        id.DPI,
+		MemoryKB:   id.MemoryKB,
+		Options:    []string{},
+		Model:      id.Model,
+		Firmware:   id.Firmware,
+		DetectedAt: &now,
+	}
+	for _, code := range id.Options {
+		if option, ok := identificationOptions[code]; ok {
+			c.Options = append(c.Options, option)
+		}
+	}
+
+	// older printers do not support SGD and do not respond at all
+	if width, err := p.optionalVar(ctx, "ezpl.print_width"); err == nil && width != "" {
+		c.PrintWidth, _ = strconv.Atoi(width)
+	}
+	if media, err := p.optionalVar(ctx, "ezpl.media_type"); err == nil && media != "" {
+		c.MediaType = mediaType(media)
+	}
+	if rfid, err := p.optionalVar(ctx, "rfid.enable"); err == nil && isOn(rfid) {
+		c.Options = append(c.Options, OptionRFID)
+	}
+	return c, nil
+}
+
+// optionalVar reads SGD variable, unknown variable is returned as empty
+// value. Printer that does not respond to SGD is not asked again.
+func (p *Printer) optionalVar(ctx context.Context, name string) (string, error) {
+	if p.noSGD {
+		return "", ErrStatusUnsupported
+	}
+	value, err := p.getvar(ctx, name)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		slog.Debug("printer does not respond to SGD", "ID", p.ID, "var", name)
+		p.noSGD = true
+	}
+	if err != nil {
+		return "", err
+	}
+	v := strings.TrimSpace(string(value))
+	if v == "?" {
+		return "", nil
+	}
+	return v, nil
+}
+
+// isOn reports whether SGD value turns the setting on, printers without
+// the option report "off" or do not know the variable.
+func isOn(value string) bool {
+	switch strings.ToLower(value) {
+	case "on", "true", "yes", "1":
+		return true
+	}
+	return false
+}
+
+// mediaType normalizes SGD media type, e.g. "gap/notch" is gap.
+func mediaType(value string) string {
+	value = strings.ToLower(value)
+	switch {
+	case strings.HasPrefix(value, "gap"), strings.HasPrefix(value, "web"):
+		return "gap"
+	case strings.HasPrefix(value, "mark"):
+		return "mark"
+	case strings.HasPrefix(value, "continuous"):
+		return "continuous"
+	}
+	return value
+}
@@ -0,0 +1,28 @@
+package printer
+
+import (
+	"testing"
+)
+
+func TestIsOn(t *testing.T) {
+	ucs := []struct {
+		value    string
+		expected bool
+	}{
+		{value: "on", expected: true},
+		{value: "ON", expected: true},
+		{value: "true", expected: true},
+		{value: "off", expected: false},
+		{value: "false", expected: false},
+		{value: "", expected: false},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.value, func(t *testing.T) {
+			if on := isOn(us.value); on != us.expected {
+				t.Errorf("expected: %v, got: %v\n", us.expected, on)
+			}
+		})
+	}
+}
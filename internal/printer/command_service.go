@@ -2,28 +2,77 @@ package printer
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
 
-var ValidationError = errors.New("Validation error")
+var (
+	ValidationError = errors.New("Validation error")
+	ErrDetection    = errors.New("cannot detect printer capabilities")
+)
+
+// detectTimeout limits detection of capabilities.
+const detectTimeout = 5 * time.Second
 
 type StorerDeleter interface {
 	Store(context.Context, *Printer) error
+	StoreMany(context.Context, []Printer) error
+	Update(context.Context, *Printer) error
+	Get(context.Context, int64) (Printer, error)
 	Delete(context.Context, int64) error
+	StoreCapabilities(context.Context, int64, Capabilities) error
 }
 
 type Queue interface {
-	Add(printers ...Printer)
-	Delete(id int64) error
+	Add(ctx context.Context, printers ...Printer) error
+	Update(ctx context.Context, printer Printer) error
+	Delete(ctx context.Context, id int64) error
 }
 
 type CreatePrinter struct {
-	Addr    string `json:"addr" validate:"required,hostname_port"`
-	Type    string `json:"type" validate:"required"`
-	Comment string `json:"comment"`
+	Addr    string     `json:"addr" validate:"required,printer_addr"`
+	Type    string     `json:"type" validate:"required"`
+	Comment string     `json:"comment"`
+	TLS     *TLSConfig `json:"tls"`
+	Mode    string     `json:"mode" validate:"omitempty,oneof=persistent per_job"`
+	Verify  bool       `json:"verify"`
+	// Capabilities are optional, e.g. found by discovery.
+	Capabilities *Capabilities `json:"capabilities"`
+}
+
+// UpdatePrinter changes only the fields that are set.
+type UpdatePrinter struct {
+	Addr    *string     `json:"addr" validate:"omitempty,printer_addr"`
+	Type    *string     `json:"type" validate:"omitempty,min=1"`
+	Comment *string     `json:"comment"`
+	TLS     OptionalTLS `json:"tls"`
+	Mode    *string     `json:"mode" validate:"omitempty,oneof=persistent per_job"`
+	Verify  *bool       `json:"verify"`
+	// Capabilities replace the current ones.
+	Capabilities *Capabilities `json:"capabilities"`
+}
+
+// OptionalTLS is TLS config of the update, unlike a pointer it tells the
+// absent field from null, which removes the config, e.g. when tls address
+// is changed to tcp.
+type OptionalTLS struct {
+	Set    bool
+	Config *TLSConfig
+}
+
+func (o *OptionalTLS) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Config = nil
+		return nil
+	}
+	o.Config = &TLSConfig{}
+	return json.Unmarshal(data, o.Config)
 }
 
 type CommandSvc struct {
@@ -32,28 +81,305 @@ type CommandSvc struct {
 	validate *validator.Validate
 }
 
-func NewCommandSvc(db StorerDeleter, queue Queue) CommandSvc {
+// DefaultFileDevices are the files printers can be registered with if no
+// other files are configured, the device files of USB printers.
+var DefaultFileDevices = []string{"/dev/usb/lp*"}
+
+// NewCommandSvc creates the service, file addresses of printers are allowed
+// only if their paths match one of fileDevices patterns, DefaultFileDevices
+// are used if none are given.
+func NewCommandSvc(db StorerDeleter, queue Queue, fileDevices []string) CommandSvc {
+	if len(fileDevices) == 0 {
+		fileDevices = DefaultFileDevices
+	}
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterValidation("printer_addr", validateAddr(validate, fileDevices))
 	return CommandSvc{
 		db:       db,
 		queue:    queue,
-		validate: validator.New(validator.WithRequiredStructEnabled()),
+		validate: validate,
+	}
+}
+
+// validateAddr checks that the address has known scheme, tcp and tls
+// addresses must have host and port, lpd and http ports are optional, file
+// addresses must match one of fileDevices.
+func validateAddr(validate *validator.Validate, fileDevices []string) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		u, err := ParseAddr(fl.Field().String())
+		if err != nil {
+			return false
+		}
+		switch u.Scheme {
+		case "tcp", "tls":
+			return validate.Var(u.Host, "hostname_port") == nil
+		case "lpd", "http", "https":
+			if u.Port() == "" {
+				return validate.Var(u.Hostname(), "hostname|ip") == nil
+			}
+			return validate.Var(u.Host, "hostname_port") == nil
+		case "file":
+			return u.Host == "" && allowedFile(u.Path, fileDevices)
+		}
+		return true
+	}
+}
+
+// allowedFile reports whether the clean absolute path matches one of the
+// patterns. Files are created if they do not exist, so any other path would
+// let clients write wherever the server can.
+func allowedFile(path string, patterns []string) bool {
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+		return false
+	}
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTLS checks that TLS config is set only for encrypted transport
+// and its certificates can be loaded.
+func validateTLS(p Printer) error {
+	if p.TLS == nil {
+		return nil
+	}
+	u, err := ParseAddr(p.Addr)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "tls" && u.Scheme != "https" {
+		return fmt.Errorf("%w: tls config is set for %s address", ValidationError, u.Scheme)
+	}
+	if _, err := p.TLS.Load(u.Hostname()); err != nil {
+		return fmt.Errorf("%w: %w", ValidationError, err)
+	}
+	return nil
+}
+
+// validateVerify checks that only ZPL printer is verified, since label
+// counter is read with SGD.
+func validateVerify(p Printer) error {
+	if p.Verify && p.Type != TypeZPL {
+		return fmt.Errorf("%w: verify is not supported for %s printer", ValidationError, p.Type)
 	}
+	return nil
 }
 
 func (svc CommandSvc) Create(ctx context.Context, cp CreatePrinter) (Printer, error) {
+	p, err := svc.newPrinter(cp)
+	if err != nil {
+		return Printer{}, err
+	}
+
+	if err := svc.store(ctx, &p); err != nil {
+		return Printer{}, err
+	}
+
+	if err := svc.queue.Add(ctx, p); err != nil {
+		return Printer{}, err
+	}
+	return p, nil
+}
+
+// CreateMany registers printers in bulk, e.g. found by discovery. All
+// printers are validated first and stored at once, so neither invalid
+// request nor failed store registers a part of them.
+func (svc CommandSvc) CreateMany(ctx context.Context, cps []CreatePrinter) ([]Printer, error) {
+	if len(cps) == 0 {
+		return nil, fmt.Errorf("%w: no printers", ValidationError)
+	}
+	printers := make([]Printer, 0, len(cps))
+	for i, cp := range cps {
+		p, err := svc.newPrinter(cp)
+		if err != nil {
+			return nil, fmt.Errorf("printer %d: %w", i, err)
+		}
+		printers = append(printers, p)
+	}
+
+	if err := svc.db.StoreMany(ctx, printers); err != nil {
+		return nil, err
+	}
+	if err := svc.queue.Add(ctx, printers...); err != nil {
+		return printers, err
+	}
+	return printers, nil
+}
+
+// store stores the printer with its capabilities.
+func (svc CommandSvc) store(ctx context.Context, p *Printer) error {
+	if err := svc.db.Store(ctx, p); err != nil {
+		return err
+	}
+	if p.Capabilities == nil {
+		return nil
+	}
+	return svc.db.StoreCapabilities(ctx, p.ID, *p.Capabilities)
+}
+
+// newPrinter validates the request and makes the printer from it.
+func (svc CommandSvc) newPrinter(cp CreatePrinter) (Printer, error) {
 	if err := svc.validate.Struct(cp); err != nil {
 		return Printer{}, fmt.Errorf("%w: %w", ValidationError, err)
 	}
 	p := New(cp.Type, cp.Addr, cp.Comment)
+	p.TLS = cp.TLS
+	if cp.Mode != "" {
+		p.Mode = cp.Mode
+	}
+	p.Verify = cp.Verify
+	p.Capabilities = cp.Capabilities
+	if err := validateTLS(p); err != nil {
+		return Printer{}, err
+	}
+	if err := validateVerify(p); err != nil {
+		return Printer{}, err
+	}
+	return p, nil
+}
 
-	if err := svc.db.Store(ctx, &p); err != nil {
+// Replace sets all fields of the printer and reconfigures its queue.
+func (svc CommandSvc) Replace(ctx context.Context, printerID int64, cp CreatePrinter) (Printer, error) {
+	p, err := svc.newPrinter(cp)
+	if err != nil {
+		return Printer{}, err
+	}
+	p.ID = printerID
+	old, err := svc.db.Get(ctx, printerID)
+	if err != nil {
+		return Printer{}, err
+	}
+	if p.Capabilities == nil {
+		// capabilities are kept, since they are not a part of the printer config
+		p.Capabilities = old.Capabilities
+	}
+	if err := svc.update(ctx, old, &p); err != nil {
 		return Printer{}, err
 	}
+	return p, nil
+}
 
-	svc.queue.Add(p)
+// Update sets the given fields of the printer and reconfigures its queue.
+func (svc CommandSvc) Update(ctx context.Context, printerID int64, up UpdatePrinter) (Printer, error) {
+	if err := svc.validate.Struct(up); err != nil {
+		return Printer{}, fmt.Errorf("%w: %w", ValidationError, err)
+	}
+	p, err := svc.db.Get(ctx, printerID)
+	if err != nil {
+		return Printer{}, err
+	}
+	old := p
+	if up.Addr != nil {
+		p.Addr = *up.Addr
+	}
+	if up.Type != nil {
+		p.Type = *up.Type
+	}
+	if up.Comment != nil {
+		p.Comment = *up.Comment
+	}
+	if up.TLS.Set {
+		p.TLS = up.TLS.Config
+	}
+	if up.Mode != nil {
+		p.Mode = *up.Mode
+	}
+	if up.Verify != nil {
+		p.Verify = *up.Verify
+	}
+	if up.Capabilities != nil {
+		p.Capabilities = up.Capabilities
+	}
+	if err := svc.update(ctx, old, &p); err != nil {
+		return Printer{}, err
+	}
+	return p, nil
+}
+
+// update reconfigures the queue before the printer is stored, since the queue
+// refuses to change the type of the printer that has pending jobs. The old
+// config is put back to the queue if the printer cannot be stored.
+func (svc CommandSvc) update(ctx context.Context, old Printer, p *Printer) error {
+	if err := validateTLS(*p); err != nil {
+		return err
+	}
+	if err := validateVerify(*p); err != nil {
+		return err
+	}
+	if err := svc.queue.Update(ctx, *p); err != nil {
+		return err
+	}
+	if err := svc.save(ctx, p); err != nil {
+		return errors.Join(err, svc.queue.Update(context.WithoutCancel(ctx), old))
+	}
+	return nil
+}
+
+// save updates the stored printer with its capabilities.
+func (svc CommandSvc) save(ctx context.Context, p *Printer) error {
+	if err := svc.db.Update(ctx, p); err != nil {
+		return err
+	}
+	if p.Capabilities == nil {
+		return nil
+	}
+	return svc.db.StoreCapabilities(ctx, p.ID, *p.Capabilities)
+}
+
+// SetCapabilities replaces capabilities of the printer, e.g. options that
+// cannot be detected.
+func (svc CommandSvc) SetCapabilities(ctx context.Context, printerID int64, c Capabilities) (Printer, error) {
+	if err := svc.validate.Struct(c); err != nil {
+		return Printer{}, fmt.Errorf("%w: %w", ValidationError, err)
+	}
+	return svc.setCapabilities(ctx, printerID, c)
+}
+
+// DetectCapabilities queries ZPL printer for its capabilities and stores
+// them. Detection uses its own connection, so it does not wait for the
+// queue of the printer.
+func (svc CommandSvc) DetectCapabilities(ctx context.Context, printerID int64) (Printer, error) {
+	p, err := svc.db.Get(ctx, printerID)
+	if err != nil {
+		return Printer{}, err
+	}
+	if p.Type != TypeZPL {
+		return Printer{}, fmt.Errorf("%w: capabilities of %s printer cannot be detected", ValidationError, p.Type)
+	}
+	probe := New(p.Type, p.Addr, "")
+	probe.ID = p.ID
+	probe.TLS = p.TLS
+	defer probe.Close()
+	ctx, cancel := context.WithTimeout(ctx, detectTimeout)
+	defer cancel()
+	c, err := probe.QueryCapabilities(ctx)
+	if err != nil {
+		return Printer{}, fmt.Errorf("%w: %w", ErrDetection, err)
+	}
+	return svc.setCapabilities(context.WithoutCancel(ctx), printerID, c)
+}
+
+func (svc CommandSvc) setCapabilities(ctx context.Context, printerID int64, c Capabilities) (Printer, error) {
+	p, err := svc.db.Get(ctx, printerID)
+	if err != nil {
+		return Printer{}, err
+	}
+	if err := svc.db.StoreCapabilities(ctx, printerID, c); err != nil {
+		return Printer{}, err
+	}
+	p.Capabilities = &c
+	if err := svc.queue.Update(ctx, p); err != nil {
+		return Printer{}, err
+	}
 	return p, nil
 }
 
 func (svc CommandSvc) Delete(ctx context.Context, printerID int64) error {
-	return svc.db.Delete(ctx, printerID)
+	if err := svc.db.Delete(ctx, printerID); err != nil {
+		return err
+	}
+	return svc.queue.Delete(ctx, printerID)
 }
@@ -1,20 +1,34 @@
 package printer
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"net"
 	"testing"
 )
 
 type TestQueue struct {
 	added   int
+	updated int
 	deleted int
+	// updateErr is returned by Update
+	updateErr error
 }
 
-func (q *TestQueue) Add(printers ...Printer) {
+func (q *TestQueue) Add(ctx context.Context, printers ...Printer) error {
 	q.added += len(printers)
+	return nil
 }
-func (q *TestQueue) Delete(id int64) error {
+func (q *TestQueue) Update(ctx context.Context, printer Printer) error {
+	if q.updateErr != nil {
+		return q.updateErr
+	}
+	q.updated++
+	return nil
+}
+func (q *TestQueue) Delete(ctx context.Context, id int64) error {
 	q.deleted++
 	return nil
 }
@@ -34,6 +48,90 @@ func TestRegister(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			desc: "file",
+			cp: CreatePrinter{
+				Addr: "file:///dev/usb/lp0",
+				Type: "ZPL",
+			},
+			expectedErr: nil,
+		},
+		{
+			desc: "file outside of devices",
+			cp: CreatePrinter{
+				Addr: "file:///etc/passwd",
+				Type: "ZPL",
+			},
+			expectedErr: ValidationError,
+		},
+		{
+			desc: "file escaping devices",
+			cp: CreatePrinter{
+				Addr: "file:///dev/usb/lp0/../../../etc/passwd",
+				Type: "ZPL",
+			},
+			expectedErr: ValidationError,
+		},
+		{
+			desc: "file with host",
+			cp: CreatePrinter{
+				Addr: "file://host/dev/usb/lp0",
+				Type: "ZPL",
+			},
+			expectedErr: ValidationError,
+		},
+		{
+			desc: "lpd without port",
+			cp: CreatePrinter{
+				Addr: "lpd://192.168.0.1/zebra",
+				Type: "ZPL",
+			},
+			expectedErr: nil,
+		},
+		{
+			desc: "tls config for tcp address",
+			cp: CreatePrinter{
+				Addr: "tcp://192.168.0.1:9100",
+				Type: "ZPL",
+				TLS:  &TLSConfig{ServerName: "printer"},
+			},
+			expectedErr: ValidationError,
+		},
+		{
+			desc: "missing CA file",
+			cp: CreatePrinter{
+				Addr: "tls://192.168.0.1:9143",
+				Type: "ZPL",
+				TLS:  &TLSConfig{CAFile: "/nonexistent/ca.crt"},
+			},
+			expectedErr: ValidationError,
+		},
+		{
+			desc: "verify ZPL",
+			cp: CreatePrinter{
+				Addr:   "0.0.0.0:8009",
+				Type:   "ZPL",
+				Verify: true,
+			},
+			expectedErr: nil,
+		},
+		{
+			desc: "verify not ZPL",
+			cp: CreatePrinter{
+				Addr:   "0.0.0.0:8009",
+				Type:   "EPL",
+				Verify: true,
+			},
+			expectedErr: ValidationError,
+		},
+		{
+			desc: "unknown scheme",
+			cp: CreatePrinter{
+				Addr: "gopher://0.0.0.0:70",
+				Type: "ZPL",
+			},
+			expectedErr: ValidationError,
+		},
 		{
 			desc: "invalid port",
 			cp: CreatePrinter{
@@ -53,7 +151,7 @@ func TestRegister(t *testing.T) {
 				t.Fatalf("got error: %s\n", err)
 			}
 			q := &TestQueue{}
-			svc := NewCommandSvc(repo, q)
+			svc := NewCommandSvc(repo, q, nil)
 
 			p, err := svc.Create(context.Background(), us.cp)
 			if !errors.Is(err, us.expectedErr) {
@@ -74,13 +172,136 @@ func TestRegister(t *testing.T) {
 	}
 }
 
+func TestRegisterFileDevices(t *testing.T) {
+	ucs := []struct {
+		desc        string
+		addr        string
+		expectedErr error
+	}{
+		{
+			desc:        "configured device",
+			addr:        "file:///var/spool/zhurd/printer1",
+			expectedErr: nil,
+		},
+		{
+			desc:        "default device",
+			addr:        "file:///dev/usb/lp0",
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "relative path",
+			addr:        "file:printer1",
+			expectedErr: ValidationError,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			repo, err := NewMemory()
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			svc := NewCommandSvc(repo, &TestQueue{}, []string{"/var/spool/zhurd/*"})
+
+			_, err = svc.Create(context.Background(), CreatePrinter{Addr: us.addr, Type: "ZPL"})
+			if !errors.Is(err, us.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+		})
+	}
+}
+
+var errStore = errors.New("connection lost")
+
+// failingRepo fails to store printers in bulk.
+type failingRepo struct {
+	*Memory
+}
+
+func (r failingRepo) StoreMany(ctx context.Context, printers []Printer) error {
+	return errStore
+}
+
+func TestCreateMany(t *testing.T) {
+	ucs := []struct {
+		desc          string
+		cps           []CreatePrinter
+		failStore     bool
+		expectedErr   error
+		expectedCount int
+	}{
+		{
+			desc: "happy path",
+			cps: []CreatePrinter{
+				{Addr: "192.168.0.10:9100", Type: "ZPL"},
+				{Addr: "192.168.0.11:9100", Type: "ZPL"},
+			},
+			expectedCount: 2,
+		},
+		{
+			desc: "one invalid",
+			cps: []CreatePrinter{
+				{Addr: "192.168.0.10:9100", Type: "ZPL"},
+				{Addr: "192.168.0.11:9100"},
+			},
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "empty",
+			expectedErr: ValidationError,
+		},
+		{
+			desc: "store failed",
+			cps: []CreatePrinter{
+				{Addr: "192.168.0.10:9100", Type: "ZPL"},
+				{Addr: "192.168.0.11:9100", Type: "ZPL"},
+			},
+			failStore:   true,
+			expectedErr: errStore,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			repo, err := NewMemory()
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			q := &TestQueue{}
+			var db StorerDeleter = repo
+			if us.failStore {
+				db = failingRepo{repo}
+			}
+			svc := NewCommandSvc(db, q, nil)
+
+			_, err = svc.CreateMany(context.Background(), us.cps)
+			if !errors.Is(err, us.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+			// nothing is registered if any printer is invalid or cannot be stored
+			printers, err := repo.List(context.Background())
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			if len(printers) != us.expectedCount {
+				t.Errorf("expected: %v, got: %v\n", us.expectedCount, len(printers))
+			}
+			if q.added != us.expectedCount {
+				t.Errorf("expected: %v, got: %v\n", us.expectedCount, q.added)
+			}
+		})
+	}
+}
+
 func TestDelete(t *testing.T) {
 	repo, err := NewMemory()
 	if err != nil {
 		t.Fatalf("got error: %s\n", err)
 	}
 	q := &TestQueue{}
-	svc := NewCommandSvc(repo, q)
+	svc := NewCommandSvc(repo, q, nil)
 	printer := &Printer{
 		Addr: "0.0.0.0:8009",
 		Type: "ZPL",
@@ -98,4 +319,263 @@ func TestDelete(t *testing.T) {
 	if !errors.Is(err, ErrNotFound) {
 		t.Errorf("expected: %v, got: %v\n", ErrNotFound, err)
 	}
+	if q.deleted != 1 {
+		t.Errorf("expected: %v, got: %v\n", 1, q.deleted)
+	}
+}
+
+func TestUpdateRejected(t *testing.T) {
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	pendingErr := errors.New("printer has pending jobs")
+	q := &TestQueue{updateErr: pendingErr}
+	svc := NewCommandSvc(repo, q, nil)
+	p := &Printer{Addr: "127.0.0.1:9100", Type: "ZPL"}
+	if err := repo.Store(context.Background(), p); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	// printer is stored only if the queue accepts the change
+	pType := "EPL"
+	if _, err := svc.Update(context.Background(), p.ID, UpdatePrinter{Type: &pType}); !errors.Is(err, pendingErr) {
+		t.Errorf("expected: %v, got: %v\n", pendingErr, err)
+	}
+	if _, err := svc.Replace(context.Background(), p.ID, CreatePrinter{Addr: p.Addr, Type: pType}); !errors.Is(err, pendingErr) {
+		t.Errorf("expected: %v, got: %v\n", pendingErr, err)
+	}
+	stored, err := repo.Get(context.Background(), p.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.Type != "ZPL" {
+		t.Errorf("expected: %s, got: %s\n", "ZPL", stored.Type)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	addr := "127.0.0.1:9101"
+	invalidAddr := "0.0.256.0:128009"
+	empty := ""
+	ucs := []struct {
+		desc        string
+		printerID   int64
+		up          UpdatePrinter
+		expected    Printer
+		expectedErr error
+	}{
+		{
+			desc:      "happy path",
+			printerID: 1,
+			up:        UpdatePrinter{Addr: &addr},
+			expected: Printer{
+				ID:      1,
+				Addr:    addr,
+				Type:    "ZPL",
+				Comment: "test printer",
+			},
+		},
+		{
+			desc:        "invalid addr",
+			printerID:   1,
+			up:          UpdatePrinter{Addr: &invalidAddr},
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "empty type",
+			printerID:   1,
+			up:          UpdatePrinter{Type: &empty},
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "not found",
+			printerID:   2,
+			up:          UpdatePrinter{Addr: &addr},
+			expectedErr: ErrNotFound,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			repo, err := NewMemory()
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			printer := New("ZPL", "0.0.0.0:8009", "test printer")
+			if err := repo.Store(context.Background(), &printer); err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			q := &TestQueue{}
+			svc := NewCommandSvc(repo, q, nil)
+
+			p, err := svc.Update(context.Background(), us.printerID, us.up)
+			if !errors.Is(err, us.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if p.ID != us.expected.ID || p.Addr != us.expected.Addr || p.Type != us.expected.Type || p.Comment != us.expected.Comment {
+				t.Errorf("expected: %v, got: %v\n", us.expected, p)
+			}
+			stored, err := repo.Get(context.Background(), us.printerID)
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			if stored.Addr != us.expected.Addr {
+				t.Errorf("expected: %v, got: %v\n", us.expected.Addr, stored.Addr)
+			}
+			if q.updated != 1 {
+				t.Errorf("expected: %v, got: %v\n", 1, q.updated)
+			}
+		})
+	}
+}
+
+func TestUpdateTLS(t *testing.T) {
+	ucs := []struct {
+		desc        string
+		body        string
+		expectedTLS bool
+		expectedErr error
+	}{
+		{
+			desc:        "kept",
+			body:        `{"comment": "updated"}`,
+			expectedTLS: true,
+		},
+		{
+			desc:        "cleared for tcp",
+			body:        `{"addr": "tcp://127.0.0.1:9100", "tls": null}`,
+			expectedTLS: false,
+		},
+		{
+			desc:        "kept for tcp",
+			body:        `{"addr": "tcp://127.0.0.1:9100"}`,
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "replaced",
+			body:        `{"tls": {"server_name": "other"}}`,
+			expectedTLS: true,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			repo, err := NewMemory()
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			printer := New("ZPL", "tls://127.0.0.1:9143", "test printer")
+			printer.TLS = &TLSConfig{ServerName: "printer"}
+			if err := repo.Store(context.Background(), &printer); err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			svc := NewCommandSvc(repo, &TestQueue{}, nil)
+
+			var up UpdatePrinter
+			if err := json.Unmarshal([]byte(us.body), &up); err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			p, err := svc.Update(context.Background(), printer.ID, up)
+			if !errors.Is(err, us.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if (p.TLS != nil) != us.expectedTLS {
+				t.Errorf("expected: %v, got: %v\n", us.expectedTLS, p.TLS)
+			}
+			stored, err := repo.Get(context.Background(), printer.ID)
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			if (stored.TLS != nil) != us.expectedTLS {
+				t.Errorf("expected: %v, got: %v\n", us.expectedTLS, stored.TLS)
+			}
+		})
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 256)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			cmd := buf[:n]
+			switch {
+			case bytes.Contains(cmd, []byte("~HI")):
+				conn.Write([]byte("\x02ZT410-200dpi,V75.20.01Z,8,8176KB,C\x03\r\n"))
+			case bytes.Contains(cmd, []byte(`"ezpl.print_width"`)):
+				conn.Write([]byte(`"832"`))
+			case bytes.Contains(cmd, []byte(`"ezpl.media_type"`)):
+				conn.Write([]byte(`"gap/notch"`))
+			case bytes.Contains(cmd, []byte(`"rfid.enable"`)):
+				// printers without RFID encoder know the variable too
+				conn.Write([]byte(`"off"`))
+			case bytes.Contains(cmd, []byte("getvar")):
+				conn.Write([]byte(`"?"`))
+			}
+		}
+	}()
+
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	q := &TestQueue{}
+	svc := NewCommandSvc(repo, q, nil)
+	p, err := svc.Create(context.Background(), CreatePrinter{Addr: l.Addr().String(), Type: "ZPL"})
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	if _, err := svc.SetCapabilities(context.Background(), p.ID, Capabilities{Language: "PCL"}); !errors.Is(err, ValidationError) {
+		t.Errorf("expected: %v, got: %v\n", ValidationError, err)
+	}
+
+	p, err = svc.DetectCapabilities(context.Background(), p.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	expected := Capabilities{Language: "ZPL", DPI: 203, PrintWidth: 832, MediaType: "gap", MemoryKB: 8176, Options: []string{OptionCutter}}
+	c := p.Capabilities
+	if c == nil || c.Language != expected.Language || c.DPI != expected.DPI || c.PrintWidth != expected.PrintWidth ||
+		c.MediaType != expected.MediaType || !c.Has(OptionCutter) || c.Has(OptionRFID) {
+		t.Errorf("expected: %+v, got: %+v\n", expected, c)
+	}
+	if q.updated != 1 {
+		t.Errorf("expected: %v, got: %v\n", 1, q.updated)
+	}
+
+	// replacing the printer config keeps its capabilities
+	p, err = svc.Replace(context.Background(), p.ID, CreatePrinter{Addr: l.Addr().String(), Type: "ZPL", Comment: "replaced"})
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	stored, err := repo.Get(context.Background(), p.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.Capabilities == nil || stored.Capabilities.DPI != 203 {
+		t.Errorf("expected capabilities to be kept, got: %+v\n", stored.Capabilities)
+	}
 }
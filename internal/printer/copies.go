@@ -0,0 +1,369 @@
+package printer
+
+import (
+	"bytes"
+	"math/big"
+	"slices"
+	"strconv"
+)
+
+// maxPQ is the largest quantity ^PQ accepts.
+const maxPQ = 99999999
+
+// Copies rewrites the document, so the printer makes the copies itself
+// instead of receiving the document per copy. Only ZPL documents with a
+// single label format are rewritten: ^PQ of the format is added or its
+// quantity is multiplied by the copies. Skip is the number of copies that
+// are already printed, starting values of ^SN and field data of ^SF are
+// advanced past them, so serial numbers continue where they stopped when
+// copies are sent in batches or the job is resumed. It reports false if
+// the document has to be resent per copy, every resent document starts
+// serial numbers over then.
+func Copies(doc []byte, pType string, skip, copies int) ([]byte, bool) {
+	if pType != TypeZPL || copies < 1 || skip < 0 {
+		return nil, false
+	}
+	if copies == 1 && skip == 0 {
+		return doc, true
+	}
+	cmds := zplCommands(doc)
+	var (
+		start, end = -1, -1
+		pq         = -1
+		fd         = -1
+		escaped    bool
+		sns, sfs   []int
+		fds        = map[int]int{}
+	)
+	for i, c := range cmds {
+		inFormat := start >= 0 && end < 0
+		switch c.name {
+		case "^XA":
+			if start >= 0 {
+				// several formats are printed one after another, not copy by copy
+				return nil, false
+			}
+			start = c.pos
+		case "^XZ":
+			if inFormat {
+				end = c.pos
+			}
+		case "^PQ":
+			if inFormat {
+				pq = i
+			}
+		case "^FD":
+			fd = i
+		case "^FS":
+			fd = -1
+		case "^FH":
+			escaped = true
+		case "^SN":
+			if inFormat {
+				sns = append(sns, i)
+			}
+		case "^SF":
+			if inFormat {
+				if fd < 0 {
+					return nil, false
+				}
+				sfs = append(sfs, i)
+				fds[i] = fd
+			}
+		case "^CC", "~CC", "^CT", "~CT":
+			// prefixes are changed, so commands cannot be found reliably
+			return nil, false
+		case "^GF", "~DY", "~DB", "~DT", "~DU":
+			// binary data may look like commands
+			if isBinary(doc, c) {
+				return nil, false
+			}
+		}
+	}
+	if start < 0 || end < 0 {
+		return nil, false
+	}
+
+	quantity, replicates := 1, 0
+	if pq >= 0 {
+		fields := bytes.Split(params(doc, cmds, pq), []byte(","))
+		var ok bool
+		if quantity, ok = number(fields[0], 1); !ok || quantity < 1 {
+			return nil, false
+		}
+		if len(fields) > 2 {
+			if replicates, ok = number(fields[2], 0); !ok {
+				return nil, false
+			}
+		}
+	}
+	if quantity > maxPQ/copies {
+		return nil, false
+	}
+
+	edits := []edit{}
+	if copies > 1 {
+		if pq < 0 {
+			edits = append(edits, edit{start: end, end: end, data: []byte("^PQ" + strconv.Itoa(copies))})
+		} else {
+			// quantity is the first parameter, the rest are kept
+			p := params(doc, cmds, pq)
+			from := cmds[pq].pos + 3
+			to := from + len(p)
+			if i := bytes.IndexByte(p, ','); i >= 0 {
+				to = from + i
+			}
+			edits = append(edits, edit{start: from, end: to, data: []byte(strconv.Itoa(quantity * copies))})
+		}
+	}
+	if skip > 0 && len(sns)+len(sfs) > 0 {
+		if replicates > 0 || (escaped && len(sfs) > 0) {
+			// labels per serial number or escaped field data cannot be counted
+			return nil, false
+		}
+		labels := int64(skip) * int64(quantity)
+		for _, i := range sns {
+			p := params(doc, cmds, i)
+			v, ok := advanceSN(p, labels)
+			if !ok {
+				return nil, false
+			}
+			from := cmds[i].pos + 3
+			edits = append(edits, edit{start: from, end: from + len(p), data: v})
+		}
+		for _, i := range sfs {
+			data := params(doc, cmds, fds[i])
+			v, ok := advanceSF(data, params(doc, cmds, i), labels)
+			if !ok {
+				return nil, false
+			}
+			from := cmds[fds[i]].pos + 3
+			edits = append(edits, edit{start: from, end: from + len(data), data: v})
+		}
+	}
+	slices.SortFunc(edits, func(a, b edit) int { return a.start - b.start })
+
+	out := make([]byte, 0, len(doc)+16)
+	last := 0
+	for _, e := range edits {
+		out = append(out, doc[last:e.start]...)
+		out = append(out, e.data...)
+		last = e.end
+	}
+	return append(out, doc[last:]...), true
+}
+
+// edit replaces the part of the document from start to end with data.
+type edit struct {
+	start, end int
+	data       []byte
+}
+
+type zplCommand struct {
+	name string
+	pos  int
+}
+
+// zplCommands lists commands of the document with their prefixes and
+// positions, names are upper cased. Field data cannot contain prefixes, so
+// they always start a command.
+func zplCommands(doc []byte) []zplCommand {
+	cmds := []zplCommand{}
+	for i := 0; i+2 < len(doc); i++ {
+		if doc[i] != '^' && doc[i] != '~' {
+			continue
+		}
+		name := string(doc[i]) + string(bytes.ToUpper(doc[i+1:i+3]))
+		cmds = append(cmds, zplCommand{name: name, pos: i})
+		i += 2
+	}
+	return cmds
+}
+
+// params returns parameters of the i-th command, they end where the next
+// command starts.
+func params(doc []byte, cmds []zplCommand, i int) []byte {
+	end := len(doc)
+	if i+1 < len(cmds) {
+		end = cmds[i+1].pos
+	}
+	return doc[cmds[i].pos+3 : end]
+}
+
+// number parses the parameter, empty parameter has the default value.
+func number(param []byte, def int) (int, bool) {
+	param = bytes.TrimSpace(param)
+	if len(param) == 0 {
+		return def, true
+	}
+	n, err := strconv.Atoi(string(param))
+	return n, err == nil
+}
+
+// advanceSN advances the starting value of ^SNv,n,z by the given number of
+// labels, the rightmost digits of the value are incremented by n per label.
+// Leading zeros of the digits are kept.
+func advanceSN(params []byte, labels int64) ([]byte, bool) {
+	fields := bytes.SplitN(params, []byte(","), 2)
+	v := fields[0]
+	increment := int64(1)
+	if len(fields) > 1 {
+		inc := bytes.SplitN(fields[1], []byte(","), 2)[0]
+		if inc = bytes.TrimSpace(inc); len(inc) > 0 {
+			n, err := strconv.ParseInt(string(inc), 10, 32)
+			if err != nil {
+				return nil, false
+			}
+			increment = n
+		}
+	}
+	to := len(v)
+	for to > 0 && (v[to-1] < '0' || v[to-1] > '9') {
+		to--
+	}
+	from := to
+	for from > 0 && v[from-1] >= '0' && v[from-1] <= '9' {
+		from--
+	}
+	if from == to {
+		return nil, false
+	}
+	start, err := strconv.ParseInt(string(v[from:to]), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	value := new(big.Int).Mul(big.NewInt(increment), big.NewInt(labels))
+	value.Add(value, big.NewInt(start))
+	if value.Sign() < 0 {
+		return nil, false
+	}
+	digits := value.String()
+	if to-from > 1 && v[from] == '0' && len(digits) < to-from {
+		digits = string(bytes.Repeat([]byte("0"), to-from-len(digits))) + digits
+	}
+	out := slices.Concat(v[:from], []byte(digits), params[to:])
+	return out, true
+}
+
+// sfDigits are digits of ^SF mask characters, % marks characters that are
+// not incremented.
+var sfDigits = map[byte]string{
+	'D': "0123456789",
+	'd': "0123456789",
+	'H': "0123456789ABCDEF",
+	'h': "0123456789abcdef",
+	'O': "01234567",
+	'o': "01234567",
+	'A': "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	'a': "abcdefghijklmnopqrstuvwxyz",
+	'N': "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	'n': "0123456789abcdefghijklmnopqrstuvwxyz",
+}
+
+// advanceSF advances field data of ^SFa,b by the given number of labels,
+// the mask a and the increment b are aligned to the right of the data and
+// the increment is added per label with carry between masked characters.
+func advanceSF(data, params []byte, labels int64) ([]byte, bool) {
+	fields := bytes.Split(params, []byte(","))
+	mask := fields[0]
+	// the increment is 1 of the rightmost masked character by default
+	var increment []byte
+	if len(fields) > 1 {
+		increment = fields[1]
+	}
+	if len(increment) > len(mask) {
+		return nil, false
+	}
+
+	// positions of the data that are incremented, from the rightmost one
+	type position struct {
+		at     int
+		digits string
+	}
+	positions := []position{}
+	for k := 1; k <= len(mask) && k <= len(data); k++ {
+		m := mask[len(mask)-k]
+		if m == '%' {
+			continue
+		}
+		digits, ok := sfDigits[m]
+		if !ok {
+			return nil, false
+		}
+		positions = append(positions, position{at: len(data) - k, digits: digits})
+	}
+
+	value, inc, modulus := new(big.Int), new(big.Int), big.NewInt(1)
+	if len(increment) == 0 {
+		inc.SetInt64(1)
+	}
+	for i := len(positions) - 1; i >= 0; i-- {
+		p := positions[i]
+		base := big.NewInt(int64(len(p.digits)))
+		d := bytes.IndexByte([]byte(p.digits), data[p.at])
+		if d < 0 {
+			return nil, false
+		}
+		value.Mul(value, base).Add(value, big.NewInt(int64(d)))
+		modulus.Mul(modulus, base)
+	}
+	for k := 1; k <= len(increment); k++ {
+		m := mask[len(mask)-k]
+		c := increment[len(increment)-k]
+		if m == '%' {
+			continue
+		}
+		if k > len(data) {
+			return nil, false
+		}
+		digits := sfDigits[m]
+		d := bytes.IndexByte([]byte(digits), c)
+		if d < 0 {
+			return nil, false
+		}
+		weight := big.NewInt(1)
+		for _, p := range positions {
+			if p.at == len(data)-k {
+				break
+			}
+			weight.Mul(weight, big.NewInt(int64(len(p.digits))))
+		}
+		inc.Add(inc, new(big.Int).Mul(weight, big.NewInt(int64(d))))
+	}
+	value.Add(value, inc.Mul(inc, big.NewInt(labels))).Mod(value, modulus)
+
+	out := bytes.Clone(data)
+	rem := new(big.Int)
+	for _, p := range positions {
+		base := big.NewInt(int64(len(p.digits)))
+		value.DivMod(value, base, rem)
+		out[p.at] = p.digits[rem.Int64()]
+	}
+	return out, true
+}
+
+// isBinary reports whether the download command carries binary data. Fonts
+// are always binary, graphics are binary if their format is B or C, ASCII
+// hex and base64 data cannot contain prefixes.
+func isBinary(doc []byte, c zplCommand) bool {
+	params := doc[c.pos+3:]
+	if i := bytes.IndexAny(params, "^~"); i >= 0 {
+		params = params[:i]
+	}
+	format := 0
+	switch c.name {
+	case "^GF":
+		// ^GFa,b,c,d,data
+	case "~DY":
+		// ~DYd:o.x,f,x,t,w,data
+		format = 1
+	default:
+		return true
+	}
+	fields := bytes.Split(params, []byte(","))
+	if len(fields) <= format {
+		return false
+	}
+	f := bytes.ToUpper(bytes.TrimSpace(fields[format]))
+	return bytes.Equal(f, []byte("B")) || bytes.Equal(f, []byte("C"))
+}
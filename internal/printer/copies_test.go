@@ -0,0 +1,201 @@
+package printer
+
+import (
+	"testing"
+)
+
+func TestCopies(t *testing.T) {
+	ucs := []struct {
+		desc     string
+		pType    string
+		doc      string
+		skip     int
+		copies   int
+		expected string
+		ok       bool
+	}{
+		{
+			desc:     "single copy",
+			pType:    TypeZPL,
+			doc:      "^XA^FDa^FS^XZ",
+			copies:   1,
+			expected: "^XA^FDa^FS^XZ",
+			ok:       true,
+		},
+		{
+			desc:     "injected",
+			pType:    TypeZPL,
+			doc:      "~DGR:LOGO.GRF,2,1,FF\n^XA^XGR:LOGO.GRF^FS^XZ\n",
+			copies:   3,
+			expected: "~DGR:LOGO.GRF,2,1,FF\n^XA^XGR:LOGO.GRF^FS^PQ3^XZ\n",
+			ok:       true,
+		},
+		{
+			desc:     "serial numbers",
+			pType:    TypeZPL,
+			doc:      "^XA^FO10,10^SN001,1,Y^FS^xz",
+			copies:   4,
+			expected: "^XA^FO10,10^SN001,1,Y^FS^PQ4^xz",
+			ok:       true,
+		},
+		{
+			desc:     "serial numbers resumed",
+			pType:    TypeZPL,
+			doc:      "^XA^FO10,10^SN001,1,Y^FS^XZ",
+			skip:     4,
+			copies:   3,
+			expected: "^XA^FO10,10^SN005,1,Y^FS^PQ3^XZ",
+			ok:       true,
+		},
+		{
+			desc:     "serial numbers of several labels",
+			pType:    TypeZPL,
+			doc:      "^XA^SN9,2^FS^PQ2^XZ",
+			skip:     3,
+			copies:   2,
+			expected: "^XA^SN21,2^FS^PQ4^XZ",
+			ok:       true,
+		},
+		{
+			desc:     "serial numbers decremented",
+			pType:    TypeZPL,
+			doc:      "^XA^SNA100,-1^FS^XZ",
+			skip:     2,
+			copies:   1,
+			expected: "^XA^SNA98,-1^FS^XZ",
+			ok:       true,
+		},
+		{
+			desc:   "serial numbers replicated",
+			pType:  TypeZPL,
+			doc:    "^XA^SN1^FS^PQ2,0,1^XZ",
+			skip:   1,
+			copies: 2,
+		},
+		{
+			desc:     "serialized field",
+			pType:    TypeZPL,
+			doc:      "^XA^FDAB09^SF%%DD,1^FS^XZ",
+			skip:     2,
+			copies:   2,
+			expected: "^XA^FDAB11^SF%%DD,1^FS^PQ2^XZ",
+			ok:       true,
+		},
+		{
+			desc:     "serialized field carry",
+			pType:    TypeZPL,
+			doc:      "^XA^FDAZ^SFAA^FS^XZ",
+			skip:     1,
+			copies:   1,
+			expected: "^XA^FDBA^SFAA^FS^XZ",
+			ok:       true,
+		},
+		{
+			desc:     "serialized field increment",
+			pType:    TypeZPL,
+			doc:      "^XA^FD0F^SFHH,2^FS^XZ",
+			skip:     1,
+			copies:   1,
+			expected: "^XA^FD11^SFHH,2^FS^XZ",
+			ok:       true,
+		},
+		{
+			desc:     "serialized field wraps",
+			pType:    TypeZPL,
+			doc:      "^XA^FD99^SFDD^FS^XZ",
+			skip:     1,
+			copies:   1,
+			expected: "^XA^FD00^SFDD^FS^XZ",
+			ok:       true,
+		},
+		{
+			desc:   "serialized field not matching mask",
+			pType:  TypeZPL,
+			doc:    "^XA^FDa^SF%%dd,1^FS^XZ",
+			skip:   1,
+			copies: 2,
+		},
+		{
+			desc:     "resumed without serial numbers",
+			pType:    TypeZPL,
+			doc:      "^XA^FDa^FS^XZ",
+			skip:     5,
+			copies:   1,
+			expected: "^XA^FDa^FS^XZ",
+			ok:       true,
+		},
+		{
+			desc:     "multiplied",
+			pType:    TypeZPL,
+			doc:      "^XA^FDa^SF%%dd,1^FS^PQ2,0,1,Y^XZ",
+			copies:   3,
+			expected: "^XA^FDa^SF%%dd,1^FS^PQ6,0,1,Y^XZ",
+			ok:       true,
+		},
+		{
+			desc:     "empty quantity",
+			pType:    TypeZPL,
+			doc:      "^XA^PQ,0,1^XZ",
+			copies:   2,
+			expected: "^XA^PQ2,0,1^XZ",
+			ok:       true,
+		},
+		{
+			desc:   "too many",
+			pType:  TypeZPL,
+			doc:    "^XA^PQ99999999^XZ",
+			copies: 2,
+		},
+		{
+			desc:   "several formats",
+			pType:  TypeZPL,
+			doc:    "^XA^FDa^FS^XZ^XA^FDb^FS^XZ",
+			copies: 2,
+		},
+		{
+			desc:   "changed prefix",
+			pType:  TypeZPL,
+			doc:    "^XA^CC+^XZ",
+			copies: 2,
+		},
+		{
+			desc:   "binary graphic",
+			pType:  TypeZPL,
+			doc:    "^XA^GFB,2,2,1,\x5eXZ^FS^XZ",
+			copies: 2,
+		},
+		{
+			desc:     "hex graphic",
+			pType:    TypeZPL,
+			doc:      "^XA^GFA,2,2,1,FF^FS^XZ",
+			copies:   2,
+			expected: "^XA^GFA,2,2,1,FF^FS^PQ2^XZ",
+			ok:       true,
+		},
+		{
+			desc:   "no format",
+			pType:  TypeZPL,
+			doc:    "~JA",
+			copies: 2,
+		},
+		{
+			desc:   "other language",
+			pType:  TypeEPL,
+			doc:    "N\nA50,0,0,1,1,1,N,\"a\"\nP1\n",
+			copies: 2,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			doc, ok := Copies([]byte(us.doc), us.pType, us.skip, us.copies)
+			if ok != us.ok {
+				t.Fatalf("expected: %v, got: %v\n", us.ok, ok)
+			}
+			if ok && string(doc) != us.expected {
+				t.Errorf("expected: %q, got: %q\n", us.expected, doc)
+			}
+		})
+	}
+}
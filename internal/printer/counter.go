@@ -0,0 +1,42 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// labelCounter is the SGD variable with the number of labels printed over
+// the life of the printer.
+const labelCounter = "odometer.total_label_count"
+
+// QueryLabelCount reads the label counter of ZPL printer with SGD getvar,
+// deadline of ctx limits the whole exchange.
+func (p *Printer) QueryLabelCount(ctx context.Context) (int64, error) {
+	value, err := p.getvar(ctx, labelCounter)
+	if err != nil {
+		return 0, err
+	}
+	return ParseLabelCount(value)
+}
+
+// ParseLabelCount parses the value of the label counter, printer that does
+// not know the variable responds with "?".
+func ParseLabelCount(value []byte) (int64, error) {
+	s := strings.TrimSpace(string(value))
+	if s == "?" || s == "" {
+		return 0, ErrStatusUnsupported
+	}
+	// some firmwares append units or group digits
+	s = strings.ReplaceAll(s, ",", "")
+	if i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		s = s[:i]
+	}
+	count, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: label counter %q", ErrInvalidStatus, bytes.TrimSpace(value))
+	}
+	return count, nil
+}
@@ -0,0 +1,91 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseLabelCount(t *testing.T) {
+	ucs := []struct {
+		desc          string
+		value         string
+		expected      int64
+		expectedError error
+	}{
+		{
+			desc:     "plain",
+			value:    "1234",
+			expected: 1234,
+		},
+		{
+			desc:     "with units",
+			value:    "1,234 LABELS",
+			expected: 1234,
+		},
+		{
+			desc:          "unknown variable",
+			value:         "?",
+			expectedError: ErrStatusUnsupported,
+		},
+		{
+			desc:          "garbage",
+			value:         "abc",
+			expectedError: ErrInvalidStatus,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			count, err := ParseLabelCount([]byte(us.value))
+			if !errors.Is(err, us.expectedError) {
+				t.Fatalf("expected: %v, got: %v\n", us.expectedError, err)
+			}
+			if count != us.expected {
+				t.Errorf("expected: %d, got: %d\n", us.expected, count)
+			}
+		})
+	}
+}
+
+func TestQueryLabelCount(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 256)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		if bytes.Contains(buf[:n], []byte(`getvar "odometer.total_label_count"`)) {
+			// response is split to check that it is read until the closing quote
+			conn.Write([]byte(`"4`))
+			time.Sleep(10 * time.Millisecond)
+			conn.Write([]byte(`2"`))
+		}
+	}()
+
+	p := New("ZPL", l.Addr().String(), "")
+	defer p.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	count, err := p.QueryLabelCount(ctx)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if count != 42 {
+		t.Errorf("expected: %d, got: %d\n", 42, count)
+	}
+}
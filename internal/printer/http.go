@@ -0,0 +1,89 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	pstprntPath = "/pstprnt"
+	// httpTimeout limits the time of posting one document, write deadline
+	// can make it shorter.
+	httpTimeout = 30 * time.Second
+)
+
+var ErrHTTPStatus = errors.New("printer responded with error status")
+
+// httpTransport posts every document to the printer, Link-OS printers
+// accept it at /pstprnt, which is used if address has no path.
+type httpTransport struct {
+	url      string
+	client   *http.Client
+	deadline time.Time
+}
+
+func dialHTTP(_ context.Context, u *url.URL, tlsConf *TLSConfig) (Transport, error) {
+	target := *u
+	if target.Path == "" || target.Path == "/" {
+		target.Path = pstprntPath
+	}
+	client := &http.Client{Timeout: httpTimeout}
+	if target.Scheme == "https" {
+		cfg, err := tlsConf.Load(target.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = cfg
+		client.Transport = transport
+	}
+	return &httpTransport{
+		url:    target.String(),
+		client: client,
+	}, nil
+}
+
+func (t *httpTransport) Write(b []byte) (int, error) {
+	ctx := context.Background()
+	if !t.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, t.deadline)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(b))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if isTLSError(err) {
+			return 0, fmt.Errorf("%w: %w", ErrTLSHandshake, err)
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// body is drained, so the connection can be reused
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("%w: %s", ErrHTTPStatus, resp.Status)
+	}
+	return len(b), nil
+}
+
+// SetWriteDeadline limits the time of posting the following documents.
+func (t *httpTransport) SetWriteDeadline(deadline time.Time) error {
+	t.deadline = deadline
+	return nil
+}
+
+func (t *httpTransport) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,58 @@
+package printer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPTransport(t *testing.T) {
+	ucs := []struct {
+		desc        string
+		status      int
+		expectedErr error
+	}{
+		{
+			desc:   "happy path",
+			status: http.StatusOK,
+		},
+		{
+			desc:        "printer error",
+			status:      http.StatusInternalServerError,
+			expectedErr: ErrHTTPStatus,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			var path, body string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				data, _ := io.ReadAll(r.Body)
+				body = string(data)
+				w.WriteHeader(us.status)
+			}))
+			defer srv.Close()
+
+			p := New("ZPL", srv.URL, "")
+			if err := p.Connect(context.Background()); err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			defer p.Close()
+			err := p.Enqueue(context.Background(), Raw("^XA^XZ"))
+			if !errors.Is(err, us.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+			if path != pstprntPath {
+				t.Errorf("expected: %s, got: %s\n", pstprntPath, path)
+			}
+			if body != "^XA^XZ" {
+				t.Errorf("expected: %s, got: %s\n", "^XA^XZ", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package printer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Identification is the response of ZPL printer to ~HI.
+type Identification struct {
+	Model    string `json:"model"`
+	Firmware string `json:"firmware"`
+	DPI      int    `json:"dpi"`
+	MemoryKB int    `json:"memory_kb"`
+	// Options are codes of recognized options, e.g. C for cutter.
+	Options []string `json:"options,omitempty"`
+}
+
+// QueryIdentification asks the printer for its model, firmware and
+// resolution, deadline of ctx limits the whole exchange.
+func (p *Printer) QueryIdentification(ctx context.Context) (Identification, error) {
+	r, err := p.reader(ctx)
+	if err != nil {
+		return Identification{}, err
+	}
+	defer r.SetReadDeadline(time.Time{})
+
+	if err := p.write(ctx, []byte("~HI")); err != nil {
+		return Identification{}, err
+	}
+	frames, err := readFrames(r, 1)
+	if err != nil {
+		p.closeBroken()
+		return Identification{}, err
+	}
+	return ParseHostIdentification(frames[0])
+}
+
+// ParseHostIdentification parses ~HI response, model, firmware version,
+// dots per millimeter and memory are followed by optional fields.
+func ParseHostIdentification(frame []byte) (Identification, error) {
+	// XXXXXX,V1.0.0,dpm,000KB,X
+	fields := strings.Split(strings.TrimSpace(string(frame)), ",")
+	if len(fields) < 4 {
+		return Identification{}, fmt.Errorf("%w: identification has %d fields", ErrInvalidStatus, len(fields))
+	}
+	dpm, err := strconv.Atoi(strings.TrimSpace(fields[2]))
+	if err != nil {
+		return Identification{}, fmt.Errorf("%w: %w", ErrInvalidStatus, err)
+	}
+	memory, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(fields[3]), "KB"))
+	if err != nil {
+		return Identification{}, fmt.Errorf("%w: %w", ErrInvalidStatus, err)
+	}
+	id := Identification{
+		Model:    strings.TrimSpace(fields[0]),
+		Firmware: strings.TrimSpace(fields[1]),
+		DPI:      dotsPerInch(dpm),
+		MemoryKB: memory,
+	}
+	for _, option := range fields[4:] {
+		if option = strings.TrimSpace(option); option != "" {
+			id.Options = append(id.Options, option)
+		}
+	}
+	return id, nil
+}
+
+// dotsPerInch converts printer resolution to the nominal DPI it is sold with.
+func dotsPerInch(dpm int) int {
+	switch dpm {
+	case 6:
+		return 152
+	case 8:
+		return 203
+	case 12:
+		return 300
+	case 24:
+		return 600
+	}
+	return int(math.Round(float64(dpm) * 25.4))
+}
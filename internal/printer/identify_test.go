@@ -0,0 +1,84 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseHostIdentification(t *testing.T) {
+	ucs := []struct {
+		desc          string
+		frame         string
+		expected      Identification
+		expectedError error
+	}{
+		{
+			desc:     "203 dpi",
+			frame:    "ZT410-200dpi,V75.20.01Z,8,8176KB",
+			expected: Identification{Model: "ZT410-200dpi", Firmware: "V75.20.01Z", DPI: 203, MemoryKB: 8176},
+		},
+		{
+			desc:     "300 dpi with options",
+			frame:    "ZD620-300dpi,V84.20.18Z,12,8192KB,C",
+			expected: Identification{Model: "ZD620-300dpi", Firmware: "V84.20.18Z", DPI: 300, MemoryKB: 8192, Options: []string{"C"}},
+		},
+		{
+			desc:          "incomplete",
+			frame:         "ZT410,V75.20.01Z",
+			expectedError: ErrInvalidStatus,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			id, err := ParseHostIdentification([]byte(us.frame))
+			if !errors.Is(err, us.expectedError) {
+				t.Fatalf("expected: %v, got: %v\n", us.expectedError, err)
+			}
+			if !reflect.DeepEqual(id, us.expected) {
+				t.Errorf("expected: %+v, got: %+v\n", us.expected, id)
+			}
+		})
+	}
+}
+
+func TestQueryIdentification(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 256)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		if bytes.Contains(buf[:n], []byte("~HI")) {
+			conn.Write([]byte("\x02ZT410-200dpi,V75.20.01Z,8,8176KB\x03\r\n"))
+		}
+	}()
+
+	p := New("ZPL", l.Addr().String(), "")
+	defer p.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	id, err := p.QueryIdentification(ctx)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if id.Model != "ZT410-200dpi" || id.DPI != 203 {
+		t.Errorf("expected: %s at %d dpi, got: %+v\n", "ZT410-200dpi", 203, id)
+	}
+}
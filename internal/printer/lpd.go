@@ -0,0 +1,144 @@
+package printer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	lpdPort         = "515"
+	lpdDefaultQueue = "lp"
+	lpdUser         = "zhurd"
+	// lpdTimeout limits the time of sending one job and waiting for
+	// acknowledgements if write deadline is not set.
+	lpdTimeout = 30 * time.Second
+)
+
+var ErrLPDRejected = errors.New("lpd server rejected the command")
+
+// lpdJobNumber is shared by all LPD transports, so concurrent jobs from
+// the same host get different file names.
+var lpdJobNumber atomic.Uint32
+
+// lpdTransport sends every document as a separate job to the LPD queue
+// (RFC 1179), address looks like lpd://host[:port]/queue.
+type lpdTransport struct {
+	addr     string
+	queue    string
+	host     string
+	deadline time.Time
+}
+
+func dialLPD(_ context.Context, u *url.URL, _ *TLSConfig) (Transport, error) {
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), lpdPort)
+	}
+	queue := strings.Trim(u.Path, "/")
+	if queue == "" {
+		queue = lpdDefaultQueue
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "zhurd"
+	}
+	// RFC 1179 limits host name in control file to 31 octets
+	if len(host) > 31 {
+		host = host[:31]
+	}
+	return &lpdTransport{addr: addr, queue: queue, host: host}, nil
+}
+
+// Write sends the document as a print job: data file first and control
+// file after it, each subcommand has to be acknowledged by the server.
+func (t *lpdTransport) Write(b []byte) (int, error) {
+	deadline := time.Now().Add(lpdTimeout)
+	if !t.deadline.IsZero() && t.deadline.Before(deadline) {
+		deadline = t.deadline
+	}
+	conn, err := net.DialTimeout("tcp", t.addr, time.Until(deadline))
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	num := lpdJobNumber.Add(1) % 1000
+	dataFile := fmt.Sprintf("dfA%03d%s", num, t.host)
+	controlFile := fmt.Sprintf("cfA%03d%s", num, t.host)
+	control := fmt.Sprintf("H%s\nP%s\nJ%s\nl%s\nU%s\nN%s\n",
+		t.host, lpdUser, dataFile, dataFile, dataFile, dataFile)
+
+	r := bufio.NewReader(conn)
+	// receive a printer job
+	if err := lpdCommand(conn, r, fmt.Sprintf("\x02%s\n", t.queue)); err != nil {
+		return 0, err
+	}
+	// receive data file
+	if err := lpdCommand(conn, r, fmt.Sprintf("\x03%d %s\n", len(b), dataFile)); err != nil {
+		return 0, err
+	}
+	if err := lpdFile(conn, r, b); err != nil {
+		return 0, err
+	}
+	// receive control file
+	if err := lpdCommand(conn, r, fmt.Sprintf("\x02%d %s\n", len(control), controlFile)); err != nil {
+		return 0, err
+	}
+	if err := lpdFile(conn, r, []byte(control)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// SetWriteDeadline limits the time of sending the following jobs, lpdTimeout
+// is used if it is longer than the deadline.
+func (t *lpdTransport) SetWriteDeadline(deadline time.Time) error {
+	t.deadline = deadline
+	return nil
+}
+
+// Close does nothing, connection is opened for every job.
+func (t *lpdTransport) Close() error {
+	return nil
+}
+
+func lpdCommand(w io.Writer, r io.ByteReader, cmd string) error {
+	if _, err := io.WriteString(w, cmd); err != nil {
+		return err
+	}
+	return lpdAck(r)
+}
+
+// lpdFile sends file contents terminated by zero octet.
+func lpdFile(w io.Writer, r io.ByteReader, b []byte) error {
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte{0}); err != nil {
+		return err
+	}
+	return lpdAck(r)
+}
+
+func lpdAck(r io.ByteReader) error {
+	ack, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if ack != 0 {
+		return fmt.Errorf("%w: code %d", ErrLPDRejected, ack)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package printer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// lpdJob is what the LPD stand-in received.
+type lpdJob struct {
+	queue   string
+	data    []byte
+	control string
+}
+
+// serveLPD accepts one job, every command is answered with ack.
+func serveLPD(t *testing.T, l net.Listener, ack byte, jobs chan<- lpdJob) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	var j lpdJob
+
+	line, err := r.ReadString('\n')
+	if err != nil || line[0] != '\x02' {
+		t.Errorf("expected receive job command, got: %q, %v\n", line, err)
+		return
+	}
+	j.queue = strings.TrimSuffix(line[1:], "\n")
+	conn.Write([]byte{ack})
+	if ack != 0 {
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Errorf("cannot read subcommand: %v\n", err)
+			return
+		}
+		fields := strings.Fields(line[1:])
+		size, err := strconv.Atoi(fields[0])
+		if err != nil {
+			t.Errorf("cannot parse file size: %v\n", err)
+			return
+		}
+		conn.Write([]byte{0})
+		file := make([]byte, size+1)
+		if _, err := io.ReadFull(r, file); err != nil {
+			t.Errorf("cannot read file: %v\n", err)
+			return
+		}
+		conn.Write([]byte{0})
+		switch line[0] {
+		case '\x02':
+			j.control = string(file[:size])
+		case '\x03':
+			j.data = file[:size]
+		}
+	}
+	jobs <- j
+}
+
+func TestLPDTransport(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	jobs := make(chan lpdJob, 1)
+	go serveLPD(t, l, 0, jobs)
+
+	p := New("ZPL", "lpd://"+l.Addr().String()+"/zebra", "")
+	if err := p.Connect(context.Background()); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer p.Close()
+	if err := p.Enqueue(context.Background(), Raw("^XA^XZ")); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	j := <-jobs
+	if j.queue != "zebra" {
+		t.Errorf("expected: %s, got: %s\n", "zebra", j.queue)
+	}
+	if string(j.data) != "^XA^XZ" {
+		t.Errorf("expected: %s, got: %s\n", "^XA^XZ", j.data)
+	}
+	if !strings.Contains(j.control, "\nldfA") {
+		t.Errorf("expected control file to print data file, got: %q\n", j.control)
+	}
+}
+
+func TestLPDTransportRejected(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	go serveLPD(t, l, 1, nil)
+
+	p := New("ZPL", "lpd://"+l.Addr().String()+"/zebra", "")
+	if err := p.Connect(context.Background()); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer p.Close()
+	if err := p.Enqueue(context.Background(), Raw("^XA^XZ")); !errors.Is(err, ErrLPDRejected) {
+		t.Errorf("expected: %v, got: %v\n", ErrLPDRejected, err)
+	}
+}
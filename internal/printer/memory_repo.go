@@ -45,6 +45,29 @@ func (m *Memory) Store(ctx context.Context, p *Printer) error {
 	return nil
 }
 
+// StoreMany stores all printers or none of them.
+func (m *Memory) StoreMany(ctx context.Context, printers []Printer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := make([][]byte, len(printers))
+	for i, p := range printers {
+		p.ID = m.nextID + int64(i)
+		if _, ok := m.m[p.ID]; ok {
+			panic("could not generate unique ID for printer")
+		}
+		var err error
+		if data[i], err = json.Marshal(p); err != nil {
+			return err
+		}
+	}
+	for i := range printers {
+		printers[i].ID = m.nextID
+		m.m[m.nextID] = data[i]
+		m.nextID += 1
+	}
+	return nil
+}
+
 func (m *Memory) List(ctx context.Context) ([]Printer, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -74,9 +97,44 @@ func (m *Memory) Get(ctx context.Context, id int64) (Printer, error) {
 	return p, nil
 }
 
+func (m *Memory) Update(ctx context.Context, p *Printer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.m[p.ID]; !ok {
+		return ErrNotFound
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	m.m[p.ID] = data
+	return nil
+}
+
+// StoreCapabilities replaces capabilities of the printer.
+func (m *Memory) StoreCapabilities(ctx context.Context, printerID int64, c Capabilities) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.m[printerID]
+	if !ok {
+		return ErrNotFound
+	}
+	var p Printer
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	p.Capabilities = &c
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	m.m[printerID] = data
+	return nil
+}
+
 func (m *Memory) Delete(ctx context.Context, id int64) error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if _, ok := m.m[id]; !ok {
 		return ErrNotFound
 	}
@@ -1,22 +1,66 @@
 package printer
 
 import (
+	"context"
 	"errors"
+	"io"
 	"log/slog"
-	"net"
+	"os"
 )
 
 type Printable interface {
 	Print(pType string) ([]byte, error)
 }
 
+// CapablePrintable is a document that is rendered by capabilities of the
+// printer, not only by its type.
+type CapablePrintable interface {
+	PrintFor(pType string, c *Capabilities) ([]byte, error)
+}
+
+// Render renders the document for the printer, capabilities may be nil if
+// they are unknown.
+func Render(document Printable, pType string, c *Capabilities) ([]byte, error) {
+	if d, ok := document.(CapablePrintable); ok {
+		return d.PrintFor(pType, c)
+	}
+	return document.Print(pType)
+}
+
+// Raw is a document that is already rendered for the printer type.
+type Raw []byte
+
+func (r Raw) Print(pType string) ([]byte, error) {
+	return r, nil
+}
+
+// Connection modes of the printer.
+const (
+	// ModePersistent keeps the connection open between jobs.
+	ModePersistent = "persistent"
+	// ModePerJob opens the connection for every job and closes it after.
+	ModePerJob = "per_job"
+)
+
 type Printer struct {
-	ID          int64
-	Addr        string
-	Type        string
-	Comment     string
-	conn        net.Conn
-	isConnected bool
+	ID      int64
+	Addr    string
+	Type    string
+	Comment string
+	TLS     *TLSConfig
+	Mode    string
+	// Verify makes the queue confirm printed labels by the label counter
+	Verify bool
+	// Capabilities are stored apart from the printer, nil if unknown
+	Capabilities *Capabilities
+	transport    Transport
+	isConnected  bool
+	// reused is set when the connection has already sent a document
+	reused bool
+	// noExtendedStatus is set if the printer does not respond to ~HQES
+	noExtendedStatus bool
+	// noSGD is set if the printer does not respond to SGD getvar
+	noSGD bool
 }
 
 func New(pType, addr, comment string) Printer {
@@ -24,22 +68,23 @@ func New(pType, addr, comment string) Printer {
 		Addr:    addr,
 		Type:    pType,
 		Comment: comment,
+		Mode:    ModePersistent,
 	}
 }
 
-func (p *Printer) Connect() error {
+// Connect opens the transport to the printer, dial is limited by ctx.
+func (p *Printer) Connect(ctx context.Context) error {
 	if p.isConnected {
-		slog.Warn("printer alredy has established connection, ignored", "ID", p.ID)
-	}
-	resolvedAddr, err := net.ResolveTCPAddr("tcp", p.Addr)
-	if err != nil {
-		return err
+		slog.Warn("printer alredy has established connection, reconnect", "ID", p.ID)
+		p.Close()
 	}
-	p.conn, err = net.DialTCP("tcp", nil, resolvedAddr)
+	transport, err := Dial(ctx, p.Addr, p.TLS)
 	if err != nil {
 		return err
 	}
+	p.transport = transport
 	p.isConnected = true
+	p.reused = false
 	return nil
 }
 
@@ -48,32 +93,76 @@ func (p *Printer) Close() error {
 		return nil
 	}
 	p.isConnected = false
-	return p.conn.Close()
+	return p.transport.Close()
 }
 
 func (p *Printer) IsConnected() bool {
 	return p.isConnected
 }
 
-func (p *Printer) Enqueue(label Printable) error {
-	if !p.isConnected {
-		return net.ErrClosed
-	}
+// IsPerJob reports whether the connection has to be closed after every job.
+func (p *Printer) IsPerJob() bool {
+	return p.Mode == ModePerJob
+}
+
+// Enqueue sends the document to the printer, deadline of ctx limits both
+// reconnect and write. The printer may close idle persistent connection,
+// so if the reused connection fails, the whole document is sent again over
+// the new one.
+func (p *Printer) Enqueue(ctx context.Context, label Printable) error {
 	bs, err := label.Print(p.Type)
 	if err != nil {
 		return err
 	}
-
-	written, err := p.conn.Write(bs)
-	if err != nil {
-		if errors.Is(err, net.ErrClosed) {
-			p.isConnected = false
+	if !p.isConnected {
+		if err := p.Connect(ctx); err != nil {
+			return err
 		}
-		return err
 	}
-	if written < len(bs) {
-		slog.Warn("document was not fully send to printer", "size", len(bs), "bytesSent", written)
+
+	reused := p.reused
+	err = p.write(ctx, bs)
+	if err != nil && reused {
+		slog.Warn("printer connection is broken, resend document over new connection", "ID", p.ID, "error", err)
+		if err := p.Connect(ctx); err != nil {
+			return err
+		}
+		err = p.write(ctx, bs)
 	}
+	return err
+}
 
+// write sends all bytes of the document, unsent bytes of short write are
+// sent again. Broken transport is closed and opened again on the next
+// document.
+func (p *Printer) write(ctx context.Context, bs []byte) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		// regular files do not support deadlines
+		if err := p.transport.SetWriteDeadline(deadline); err != nil && !errors.Is(err, os.ErrNoDeadline) {
+			p.closeBroken()
+			return err
+		}
+	}
+	for len(bs) > 0 {
+		written, err := p.transport.Write(bs)
+		if err == nil && written == 0 {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			p.closeBroken()
+			return err
+		}
+		if written < len(bs) {
+			slog.Debug("document was not fully sent to printer, send the rest", "ID", p.ID, "size", len(bs), "bytesSent", written)
+		}
+		bs = bs[written:]
+	}
+	p.reused = true
 	return nil
 }
+
+func (p *Printer) closeBroken() {
+	if err := p.Close(); err != nil {
+		slog.Warn("cannot close printer transport", "ID", p.ID, "error", err)
+	}
+}
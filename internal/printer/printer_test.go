@@ -0,0 +1,76 @@
+package printer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// fakeTransport accepts at most chunk bytes per write and fails after limit
+// of writes.
+type fakeTransport struct {
+	chunk   int
+	limit   int
+	writes  int
+	written []byte
+}
+
+func (t *fakeTransport) Write(b []byte) (int, error) {
+	if t.writes >= t.limit {
+		return 0, net.ErrClosed
+	}
+	t.writes++
+	n := min(len(b), t.chunk)
+	t.written = append(t.written, b[:n]...)
+	return n, nil
+}
+
+func (t *fakeTransport) SetWriteDeadline(time.Time) error { return nil }
+
+func (t *fakeTransport) Close() error { return nil }
+
+func TestEnqueue(t *testing.T) {
+	var dialed []*fakeTransport
+	transports["fake"] = func(context.Context, *url.URL, *TLSConfig) (Transport, error) {
+		// every connection can send one document of 6 bytes by 2 bytes
+		tr := &fakeTransport{chunk: 2, limit: 3}
+		dialed = append(dialed, tr)
+		return tr, nil
+	}
+	defer delete(transports, "fake")
+
+	p := New("ZPL", "fake://printer", "")
+	for i := 0; i < 2; i++ {
+		if err := p.Enqueue(context.Background(), Raw("^XA^XZ")); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+
+	// the second document is resent over the new connection
+	if len(dialed) != 2 {
+		t.Fatalf("expected: %d, got: %d\n", 2, len(dialed))
+	}
+	for _, tr := range dialed {
+		if string(tr.written) != "^XA^XZ" {
+			t.Errorf("expected: %s, got: %s\n", "^XA^XZ", tr.written)
+		}
+	}
+}
+
+func TestEnqueueFailed(t *testing.T) {
+	transports["fake"] = func(context.Context, *url.URL, *TLSConfig) (Transport, error) {
+		return &fakeTransport{chunk: 2, limit: 1}, nil
+	}
+	defer delete(transports, "fake")
+
+	p := New("ZPL", "fake://printer", "")
+	if err := p.Enqueue(context.Background(), Raw("^XA^XZ")); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("expected: %v, got: %v\n", net.ErrClosed, err)
+	}
+	if p.IsConnected() {
+		t.Errorf("expected broken connection to be closed\n")
+	}
+}
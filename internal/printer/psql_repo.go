@@ -5,9 +5,13 @@ import (
 	"errors"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// foreignKeyViolation is the code of postgres error.
+const foreignKeyViolation = "23503"
+
 type PSQL struct {
 	pool *pgxpool.Pool
 }
@@ -16,17 +20,48 @@ func NewPSQL(pool *pgxpool.Pool) (*PSQL, error) {
 	return &PSQL{pool: pool}, nil
 }
 
+const insertPrinter = "INSERT INTO printers (addr, type, comment, tls, mode, verify) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+
 func (repo *PSQL) Store(ctx context.Context, p *Printer) error {
-	sql := "INSERT INTO printers (addr, type, comment) VALUES ($1, $2, $3) RETURNING id"
-	row := repo.pool.QueryRow(ctx, sql, p.Addr, p.Type, p.Comment)
+	row := repo.pool.QueryRow(ctx, insertPrinter, p.Addr, p.Type, p.Comment, p.TLS, p.Mode, p.Verify)
 	if err := row.Scan(&p.ID); err != nil {
 		return err
 	}
 	return nil
 }
 
+// StoreMany stores printers with their capabilities in one transaction, so
+// either all of them are stored or none.
+func (repo *PSQL) StoreMany(ctx context.Context, printers []Printer) error {
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ids := make([]int64, len(printers))
+	for i, p := range printers {
+		row := tx.QueryRow(ctx, insertPrinter, p.Addr, p.Type, p.Comment, p.TLS, p.Mode, p.Verify)
+		if err := row.Scan(&ids[i]); err != nil {
+			return err
+		}
+		if p.Capabilities != nil {
+			if err := storeCapabilities(ctx, tx, ids[i], *p.Capabilities); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	for i := range printers {
+		printers[i].ID = ids[i]
+	}
+	return nil
+}
+
 func (repo *PSQL) List(ctx context.Context) ([]Printer, error) {
-	sql := "SELECT id, addr, type, comment FROM printers"
+	sql := "SELECT id, addr, type, comment, tls, mode, verify FROM printers"
 	rows, err := repo.pool.Query(ctx, sql)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -38,32 +73,125 @@ func (repo *PSQL) List(ctx context.Context) ([]Printer, error) {
 	printers := []Printer{}
 	for rows.Next() {
 		p := Printer{}
-		if err := rows.Scan(&p.ID, &p.Addr, &p.Type, &p.Comment); err != nil {
+		if err := rows.Scan(&p.ID, &p.Addr, &p.Type, &p.Comment, &p.TLS, &p.Mode, &p.Verify); err != nil {
 			return nil, err
 		}
 		printers = append(printers, p)
 	}
+	rows.Close()
+
+	capabilities, err := repo.listCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range printers {
+		if c, ok := capabilities[printers[i].ID]; ok {
+			printers[i].Capabilities = &c
+		}
+	}
 	return printers, nil
 }
 
 func (repo *PSQL) Get(ctx context.Context, id int64) (Printer, error) {
-	sql := "SELECT id, addr, type, comment FROM printers WHERE id = $1"
+	sql := "SELECT id, addr, type, comment, tls, mode, verify FROM printers WHERE id = $1"
 	row := repo.pool.QueryRow(ctx, sql, id)
 	p := Printer{}
-	if err := row.Scan(&p.ID, &p.Addr, &p.Type, &p.Comment); err != nil {
+	if err := row.Scan(&p.ID, &p.Addr, &p.Type, &p.Comment, &p.TLS, &p.Mode, &p.Verify); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return Printer{}, ErrNotFound
 		}
 		return Printer{}, err
 	}
+
+	sql = "SELECT " + capabilitiesColumns + " FROM printer_capabilities WHERE printer_id = $1"
+	c, err := scanCapabilities(repo.pool.QueryRow(ctx, sql, id))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return Printer{}, err
+	}
+	if err == nil {
+		p.Capabilities = &c
+	}
 	return p, nil
 }
 
+func (repo *PSQL) Update(ctx context.Context, p *Printer) error {
+	sql := "UPDATE printers SET addr = $2, type = $3, comment = $4, tls = $5, mode = $6, verify = $7 WHERE id = $1"
+	tag, err := repo.pool.Exec(ctx, sql, p.ID, p.Addr, p.Type, p.Comment, p.TLS, p.Mode, p.Verify)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 func (repo *PSQL) Delete(ctx context.Context, id int64) error {
 	sql := "DELETE FROM printers WHERE id = $1"
-	_, err := repo.pool.Exec(ctx, sql, id)
+	tag, err := repo.pool.Exec(ctx, sql, id)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+const capabilitiesColumns = "language, dpi, print_width, media_type, memory_kb, options, model, firmware, detected_at"
+
+func scanCapabilities(row pgx.Row) (Capabilities, error) {
+	c := Capabilities{}
+	err := row.Scan(&c.Language, &c.DPI, &c.PrintWidth, &c.MediaType, &c.MemoryKB, &c.Options, &c.Model, &c.Firmware, &c.DetectedAt)
+	return c, err
+}
+
+func (repo *PSQL) listCapabilities(ctx context.Context) (map[int64]Capabilities, error) {
+	sql := "SELECT printer_id, " + capabilitiesColumns + " FROM printer_capabilities"
+	rows, err := repo.pool.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	capabilities := map[int64]Capabilities{}
+	for rows.Next() {
+		var (
+			id int64
+			c  Capabilities
+		)
+		if err := rows.Scan(&id, &c.Language, &c.DPI, &c.PrintWidth, &c.MediaType, &c.MemoryKB, &c.Options, &c.Model, &c.Firmware, &c.DetectedAt); err != nil {
+			return nil, err
+		}
+		capabilities[id] = c
+	}
+	return capabilities, rows.Err()
+}
+
+// StoreCapabilities replaces capabilities of the printer.
+func (repo *PSQL) StoreCapabilities(ctx context.Context, printerID int64, c Capabilities) error {
+	return storeCapabilities(ctx, repo.pool, printerID, c)
+}
+
+// execer is the pool or the transaction.
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func storeCapabilities(ctx context.Context, db execer, printerID int64, c Capabilities) error {
+	if c.Options == nil {
+		c.Options = []string{}
+	}
+	sql := "INSERT INTO printer_capabilities (printer_id, " + capabilitiesColumns + ") " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) " +
+		"ON CONFLICT (printer_id) DO UPDATE SET language = $2, dpi = $3, print_width = $4, media_type = $5, " +
+		"memory_kb = $6, options = $7, model = $8, firmware = $9, detected_at = $10"
+	_, err := db.Exec(ctx, sql, printerID, c.Language, c.DPI, c.PrintWidth, c.MediaType, c.MemoryKB, c.Options, c.Model, c.Firmware, c.DetectedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return ErrNotFound
+		}
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,52 @@
+package printer
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// getvar reads the value of SGD variable, deadline of ctx limits the whole
+// exchange.
+func (p *Printer) getvar(ctx context.Context, name string) ([]byte, error) {
+	r, err := p.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.SetReadDeadline(time.Time{})
+
+	if err := p.write(ctx, []byte(`! U1 getvar "`+name+`"`+"\r\n")); err != nil {
+		return nil, err
+	}
+	value, err := readQuoted(r)
+	if err != nil {
+		p.closeBroken()
+		return nil, err
+	}
+	return value, nil
+}
+
+// readQuoted reads SGD response, the value is enclosed in double quotes.
+func readQuoted(r io.Reader) ([]byte, error) {
+	var (
+		value []byte
+		in    bool
+		buf   = make([]byte, 64)
+	)
+	for {
+		read, err := r.Read(buf)
+		for _, b := range buf[:read] {
+			switch {
+			case b == '"' && !in:
+				in = true
+			case b == '"':
+				return value, nil
+			case in:
+				value = append(value, b)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
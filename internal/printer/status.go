@@ -0,0 +1,264 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TypeZPL is the type of printers that report status on ~HS and ~HQES.
+const TypeZPL = "ZPL"
+
+const (
+	stx = 0x02
+	etx = 0x03
+)
+
+var (
+	ErrStatusUnsupported = errors.New("printer transport cannot read status")
+	ErrInvalidStatus     = errors.New("invalid status response")
+)
+
+// Status is the state reported by ZPL printer in response to ~HS and ~HQES.
+type Status struct {
+	PaperOut         bool `json:"paper_out"`
+	Paused           bool `json:"paused"`
+	HeadOpen         bool `json:"head_open"`
+	RibbonOut        bool `json:"ribbon_out"`
+	UnderTemperature bool `json:"under_temperature"`
+	OverTemperature  bool `json:"over_temperature"`
+	CorruptRAM       bool `json:"corrupt_ram"`
+	BufferFull       bool `json:"buffer_full"`
+	// FormatsInBuffer is the number of formats received, but not printed yet.
+	FormatsInBuffer int `json:"formats_in_buffer"`
+	// LabelsRemaining is the number of labels left to print in the batch.
+	LabelsRemaining int `json:"labels_remaining"`
+	// Errors and Warnings are reported by ~HQES, older printers do not support it.
+	Errors   []string `json:"errors,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Ready reports whether the printer can print, paused printer only
+// collects formats in its buffer.
+func (s Status) Ready() bool {
+	return !s.PaperOut &&
+		!s.Paused &&
+		!s.HeadOpen &&
+		!s.RibbonOut &&
+		!s.OverTemperature &&
+		!s.CorruptRAM &&
+		len(s.Errors) == 0
+}
+
+// statusReader is implemented by transports that can read responses of the printer.
+type statusReader interface {
+	io.Reader
+	SetReadDeadline(t time.Time) error
+}
+
+// QueryStatus asks the printer for its status, deadline of ctx limits the
+// whole exchange. Extended status is optional, so its failure is ignored.
+func (p *Printer) QueryStatus(ctx context.Context) (Status, error) {
+	r, err := p.reader(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	defer r.SetReadDeadline(time.Time{})
+
+	if err := p.write(ctx, []byte("~HS")); err != nil {
+		return Status{}, err
+	}
+	frames, err := readFrames(r, 3)
+	if err != nil {
+		p.closeBroken()
+		return Status{}, err
+	}
+	status, err := ParseHostStatus(frames)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if p.noExtendedStatus {
+		return status, nil
+	}
+	if err := p.write(ctx, []byte("~HQES")); err != nil {
+		return Status{}, err
+	}
+	frames, err = readFrames(r, 1)
+	if err != nil {
+		// late response would break the next query, so connection is opened again
+		slog.Debug("printer does not report extended status", "ID", p.ID, "error", err)
+		p.noExtendedStatus = true
+		p.closeBroken()
+		return status, nil
+	}
+	if err := status.parseExtended(frames[0]); err != nil {
+		slog.Debug("cannot parse extended status", "ID", p.ID, "error", err)
+	}
+	return status, nil
+}
+
+// reader connects to the printer if needed and returns the transport to
+// read responses until the deadline of ctx.
+func (p *Printer) reader(ctx context.Context) (statusReader, error) {
+	if !p.isConnected {
+		if err := p.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+	r, ok := p.transport.(statusReader)
+	if !ok {
+		return nil, ErrStatusUnsupported
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := r.SetReadDeadline(deadline); err != nil {
+			if errors.Is(err, os.ErrNoDeadline) {
+				return nil, ErrStatusUnsupported
+			}
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// readFrames reads n responses framed by STX and ETX.
+func readFrames(r io.Reader, n int) ([][]byte, error) {
+	var (
+		frames [][]byte
+		frame  []byte
+		in     bool
+		buf    = make([]byte, 256)
+	)
+	for len(frames) < n {
+		read, err := r.Read(buf)
+		for _, b := range buf[:read] {
+			switch {
+			case b == stx:
+				in = true
+				frame = frame[:0]
+			case b == etx && in:
+				in = false
+				frames = append(frames, bytes.Clone(frame))
+			case in:
+				frame = append(frame, b)
+			}
+		}
+		if err != nil && len(frames) < n {
+			return nil, err
+		}
+	}
+	return frames, nil
+}
+
+// ParseHostStatus parses three strings of ~HS response.
+func ParseHostStatus(frames [][]byte) (Status, error) {
+	if len(frames) != 3 {
+		return Status{}, fmt.Errorf("%w: expected 3 strings, got %d", ErrInvalidStatus, len(frames))
+	}
+	// aaa,b,c,dddd,eee,f,g,h,iii,j,k,l
+	first := strings.Split(strings.TrimSpace(string(frames[0])), ",")
+	if len(first) < 12 {
+		return Status{}, fmt.Errorf("%w: first string has %d fields", ErrInvalidStatus, len(first))
+	}
+	// mmm,n,o,p,q,r,s,t,uuuuuuuu,v,www
+	second := strings.Split(strings.TrimSpace(string(frames[1])), ",")
+	if len(second) < 9 {
+		return Status{}, fmt.Errorf("%w: second string has %d fields", ErrInvalidStatus, len(second))
+	}
+
+	formats, err := strconv.Atoi(first[4])
+	if err != nil {
+		return Status{}, fmt.Errorf("%w: %w", ErrInvalidStatus, err)
+	}
+	remaining, err := strconv.Atoi(second[8])
+	if err != nil {
+		return Status{}, fmt.Errorf("%w: %w", ErrInvalidStatus, err)
+	}
+	return Status{
+		PaperOut:         first[1] == "1",
+		Paused:           first[2] == "1",
+		BufferFull:       first[5] == "1",
+		CorruptRAM:       first[9] == "1",
+		UnderTemperature: first[10] == "1",
+		OverTemperature:  first[11] == "1",
+		HeadOpen:         second[2] == "1",
+		RibbonOut:        second[3] == "1",
+		FormatsInBuffer:  formats,
+		LabelsRemaining:  remaining,
+	}, nil
+}
+
+// errorFlags and warningFlags are bits of the ~HQES response.
+var (
+	errorFlags = []string{
+		"media out",
+		"ribbon out",
+		"head open",
+		"cutter fault",
+		"printhead over temperature",
+		"motor over temperature",
+		"bad printhead element",
+		"printhead detection error",
+		"invalid firmware config",
+		"printhead thermistor open",
+	}
+	warningFlags = []string{
+		"need to calibrate media",
+		"clean printhead",
+		"replace printhead",
+		"paper near end",
+	}
+)
+
+// parseExtended parses ~HQES response such as
+//
+//	PRINTER STATUS
+//	   ERRORS:         1 00000000 00000005
+//	   WARNINGS:       0 00000000 00000000
+func (s *Status) parseExtended(frame []byte) error {
+	found := false
+	for _, line := range strings.Split(string(frame), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidStatus, err)
+		}
+		switch fields[0] {
+		case "ERRORS:":
+			s.Errors = flagNames(flags, errorFlags)
+			found = true
+		case "WARNINGS:":
+			s.Warnings = flagNames(flags, warningFlags)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: no errors and warnings", ErrInvalidStatus)
+	}
+	return nil
+}
+
+func flagNames(flags uint64, names []string) []string {
+	var set []string
+	for i, name := range names {
+		if flags&(1<<i) != 0 {
+			set = append(set, name)
+		}
+	}
+	for i := len(names); i < 32; i++ {
+		if flags&(1<<i) != 0 {
+			set = append(set, fmt.Sprintf("unknown flag 0x%08x", uint64(1)<<i))
+		}
+	}
+	return set
+}
@@ -0,0 +1,128 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	hsReady    = "\x02030,0,0,0245,000,0,0,0,000,0,0,0\x03\r\n\x02001,0,0,0,1,2,4,0,00000000,1,000\x03\r\n\x021234,0\x03\r\n"
+	hsPaperOut = "\x02030,1,0,0245,002,0,0,0,000,0,0,0\x03\r\n\x02001,0,1,0,1,2,4,0,00000003,1,000\x03\r\n\x021234,0\x03\r\n"
+	hqesErrors = "\x02\r\nPRINTER STATUS\r\n   ERRORS:         1 00000000 00000005\r\n   WARNINGS:       1 00000000 00000002\r\n\x03\r\n"
+)
+
+// serveStatus answers ~HS and ~HQES of one connection.
+func serveStatus(l net.Listener, hs, hqes string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	buf := make([]byte, 256)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		cmd := buf[:n]
+		switch {
+		case bytes.Contains(cmd, []byte("~HQES")):
+			if hqes != "" {
+				conn.Write([]byte(hqes))
+			}
+		case bytes.Contains(cmd, []byte("~HS")):
+			conn.Write([]byte(hs))
+		}
+	}
+}
+
+func TestParseHostStatus(t *testing.T) {
+	frames, err := readFrames(bytes.NewBufferString(hsPaperOut), 3)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	status, err := ParseHostStatus(frames)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	expected := Status{PaperOut: true, HeadOpen: true, FormatsInBuffer: 2, LabelsRemaining: 3}
+	if !status.PaperOut || !status.HeadOpen || status.Paused || status.FormatsInBuffer != 2 || status.LabelsRemaining != 3 {
+		t.Errorf("expected: %+v, got: %+v\n", expected, status)
+	}
+	if status.Ready() {
+		t.Errorf("expected printer not to be ready\n")
+	}
+
+	if _, err := ParseHostStatus(frames[:2]); err == nil {
+		t.Errorf("expected error for incomplete response\n")
+	}
+}
+
+func TestQueryStatus(t *testing.T) {
+	ucs := []struct {
+		desc             string
+		hs               string
+		hqes             string
+		expectedReady    bool
+		expectedErrors   []string
+		expectedWarnings []string
+	}{
+		{
+			desc:          "ready",
+			hs:            hsReady,
+			expectedReady: true,
+		},
+		{
+			desc:             "extended errors",
+			hs:               hsReady,
+			hqes:             hqesErrors,
+			expectedReady:    false,
+			expectedErrors:   []string{"media out", "head open"},
+			expectedWarnings: []string{"clean printhead"},
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			defer l.Close()
+			go serveStatus(l, us.hs, us.hqes)
+
+			p := New("ZPL", l.Addr().String(), "")
+			defer p.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			status, err := p.QueryStatus(ctx)
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			if status.Ready() != us.expectedReady {
+				t.Errorf("expected: %v, got: %v\n", us.expectedReady, status.Ready())
+			}
+			if !slices.Equal(status.Errors, us.expectedErrors) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedErrors, status.Errors)
+			}
+			if !slices.Equal(status.Warnings, us.expectedWarnings) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedWarnings, status.Warnings)
+			}
+		})
+	}
+}
+
+func TestQueryStatusUnsupported(t *testing.T) {
+	// lpd transport connects only to send a job
+	p := New("ZPL", "lpd://127.0.0.1:1/zebra", "")
+	defer p.Close()
+	if _, err := p.QueryStatus(context.Background()); !errors.Is(err, ErrStatusUnsupported) {
+		t.Errorf("expected: %v, got: %v\n", ErrStatusUnsupported, err)
+	}
+}
@@ -0,0 +1,95 @@
+package printer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+)
+
+var ErrTLSHandshake = errors.New("tls handshake with printer failed")
+
+// TLSConfig configures TLS connection to the printer, system roots are
+// used if CA file is not set.
+type TLSConfig struct {
+	// CAFile pins the certificate authority of the printer.
+	CAFile string `json:"ca_file,omitempty"`
+	// CertFile and KeyFile are the client certificate, if printer requires it.
+	CertFile   string `json:"cert_file,omitempty"`
+	KeyFile    string `json:"key_file,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
+}
+
+// Load reads certificates and builds the TLS config for the host.
+func (c *TLSConfig) Load(host string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName: host,
+		MinVersion: tls.VersionTLS12,
+	}
+	if c == nil {
+		return cfg, nil
+	}
+	if c.ServerName != "" {
+		cfg.ServerName = c.ServerName
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// dialTLS opens raw socket to the printer over TLS, address looks like
+// tls://host:port.
+func dialTLS(ctx context.Context, u *url.URL, tlsConf *TLSConfig) (Transport, error) {
+	cfg, err := tlsConf.Load(u.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %w", ErrTLSHandshake, err)
+	}
+	return tlsConn, nil
+}
+
+// isTLSError reports whether err is caused by TLS handshake.
+func isTLSError(err error) bool {
+	var (
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &recordErr) ||
+		errors.As(err, &alertErr) ||
+		errors.As(err, &verifyErr) ||
+		errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr)
+}
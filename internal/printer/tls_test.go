@@ -0,0 +1,156 @@
+package printer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is certificate signed by the test CA.
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+func newTestCert(t *testing.T, dir, name string, ca *testCert, tmpl *x509.Certificate) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.Subject = pkix.Name{CommonName: name}
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	parent, signer := tmpl, key
+	if ca != nil {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	c := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(c.certFile, certPEM, 0o600); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if err := os.WriteFile(c.keyFile, keyPEM, 0o600); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	return c
+}
+
+func TestTLSTransport(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil, &x509.Certificate{
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	server := newTestCert(t, dir, "server", ca, &x509.Certificate{
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	client := newTestCert(t, dir, "client", ca, &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{server.cert.Raw},
+			PrivateKey:  server.key,
+		}},
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	})
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	received := make(chan []byte, 3)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			data, _ := io.ReadAll(c)
+			c.Close()
+			received <- data
+		}
+	}()
+
+	ucs := []struct {
+		desc        string
+		tls         *TLSConfig
+		expectedErr error
+	}{
+		{
+			desc: "pinned CA and client certificate",
+			tls: &TLSConfig{
+				CAFile:   ca.certFile,
+				CertFile: client.certFile,
+				KeyFile:  client.keyFile,
+			},
+		},
+		{
+			desc:        "unknown CA",
+			tls:         nil,
+			expectedErr: ErrTLSHandshake,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			p := New("ZPL", "tls://"+l.Addr().String(), "")
+			p.TLS = us.tls
+			err := p.Connect(context.Background())
+			if !errors.Is(err, us.expectedErr) {
+				t.Fatalf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if err := p.Enqueue(context.Background(), Raw("^XA^XZ")); err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			p.Close()
+			if data := <-received; string(data) != "^XA^XZ" {
+				t.Errorf("expected: %s, got: %s\n", "^XA^XZ", data)
+			}
+		})
+	}
+}
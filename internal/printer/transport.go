@@ -0,0 +1,83 @@
+package printer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var ErrUnknownScheme = errors.New("unknown address scheme")
+
+// Transport delivers rendered documents to the printer.
+type Transport interface {
+	Write(b []byte) (int, error)
+	// SetWriteDeadline limits the time of the following writes.
+	SetWriteDeadline(t time.Time) error
+	Close() error
+}
+
+// Dialer opens the transport to the address of the printer, TLS config is
+// used by transports that support encryption.
+type Dialer func(ctx context.Context, u *url.URL, tlsConf *TLSConfig) (Transport, error)
+
+// transports maps address schemes to dialers.
+var transports = map[string]Dialer{
+	"tcp":   dialTCP,
+	"file":  openFile,
+	"lpd":   dialLPD,
+	"http":  dialHTTP,
+	"https": dialHTTP,
+	"tls":   dialTLS,
+}
+
+// ParseAddr parses the printer address, address without scheme such as
+// 192.168.0.1:9100 is a raw tcp socket.
+func ParseAddr(addr string) (*url.URL, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "tcp://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := transports[u.Scheme]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownScheme, u.Scheme)
+	}
+	return u, nil
+}
+
+// Dial opens the transport selected by the scheme of the address.
+func Dial(ctx context.Context, addr string, tlsConf *TLSConfig) (Transport, error) {
+	u, err := ParseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	return transports[u.Scheme](ctx, u, tlsConf)
+}
+
+func dialTCP(ctx context.Context, u *url.URL, _ *TLSConfig) (Transport, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", u.Host)
+}
+
+// openFile appends documents to the file, it works for device files of
+// printers attached by USB such as /dev/usb/lp0 as well. File is opened for
+// reading too if it is allowed, so the status of USB printer can be read.
+func openFile(_ context.Context, u *url.URL, _ *TLSConfig) (Transport, error) {
+	if u.Path == "" {
+		return nil, fmt.Errorf("file address has no path: %s", u)
+	}
+	f, err := os.OpenFile(u.Path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o644)
+	if errors.Is(err, os.ErrPermission) {
+		f, err = os.OpenFile(u.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
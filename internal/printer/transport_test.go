@@ -0,0 +1,117 @@
+package printer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileTransport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.zpl")
+	p := New("ZPL", "file://"+path, "")
+	if err := p.Connect(context.Background()); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := p.Enqueue(context.Background(), Raw("^XA^XZ")); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if string(data) != "^XA^XZ^XA^XZ" {
+		t.Errorf("expected: %s, got: %s\n", "^XA^XZ^XA^XZ", data)
+	}
+}
+
+func TestTCPTransport(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	received := make(chan []byte)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		data, _ := io.ReadAll(c)
+		received <- data
+	}()
+
+	p := New("ZPL", "tcp://"+l.Addr().String(), "")
+	if err := p.Connect(context.Background()); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if err := p.Enqueue(context.Background(), Raw("^XA^XZ")); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	p.Close()
+	if data := <-received; string(data) != "^XA^XZ" {
+		t.Errorf("expected: %s, got: %s\n", "^XA^XZ", data)
+	}
+}
+
+func TestParseAddr(t *testing.T) {
+	ucs := []struct {
+		desc           string
+		addr           string
+		expectedScheme string
+		expectedErr    error
+	}{
+		{
+			desc:           "address without scheme",
+			addr:           "192.168.0.1:9100",
+			expectedScheme: "tcp",
+		},
+		{
+			desc:           "tcp",
+			addr:           "tcp://192.168.0.1:9100",
+			expectedScheme: "tcp",
+		},
+		{
+			desc:           "file",
+			addr:           "file:///dev/usb/lp0",
+			expectedScheme: "file",
+		},
+		{
+			desc:           "lpd",
+			addr:           "lpd://192.168.0.1/zebra",
+			expectedScheme: "lpd",
+		},
+		{
+			desc:           "https",
+			addr:           "https://192.168.0.1/pstprnt",
+			expectedScheme: "https",
+		},
+		{
+			desc:        "unknown scheme",
+			addr:        "gopher://192.168.0.1:70",
+			expectedErr: ErrUnknownScheme,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			u, err := ParseAddr(us.addr)
+			if !errors.Is(err, us.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", us.expectedErr, err)
+			}
+			if err == nil && u.Scheme != us.expectedScheme {
+				t.Errorf("expected: %v, got: %v\n", us.expectedScheme, u.Scheme)
+			}
+		})
+	}
+}
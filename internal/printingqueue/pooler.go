@@ -1,108 +1,774 @@
 package printingqueue
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
+	"zhurd/internal/job"
+	"zhurd/internal/pool"
 	"zhurd/internal/printer"
 )
 
+const (
+	// retryDelay is used to postpone the scheduled task when its queue is full.
+	retryDelay = 5 * time.Second
+	// failoverChecks is the number of health checks of pool members
+	// within FailoverAfter.
+	failoverChecks = 4
+)
+
+// command is executed by the goroutine that runs the pooler, so it has
+// exclusive access to the queues and the scheduler.
+type command struct {
+	fn   func(ctx context.Context) error
+	resp chan error
+}
+
 type Pooler struct {
 	bufferSize int
+	retry      job.RetryPolicy
+	conn       ConnPolicy
+	jobs       JobStorer
 	wg         sync.WaitGroup
-	queues     map[int64]*Queue
-	addCh      chan *Queue
-	deleteCh   chan int64
-	tasksCh    chan Task
+	commands   chan command
+	retryCh    chan Task
+	done       chan struct{}
+
+	// owned by Run
+	queues    map[int64]*Queue
+	scheduler *scheduler
+	pools     map[int64]pool.Pool
+	// downSince is the time printers of pools were first seen unhealthy
+	downSince map[int64]time.Time
+	// canceled are failed jobs canceled on their way to retry
+	canceled map[int64]bool
 }
 
-func NewPooler(bufferSize int) *Pooler {
+// NewPooler creates pooler, retry policy is applied to jobs that have no own
+// policy, connection policy is applied to all queues.
+func NewPooler(bufferSize int, retry job.RetryPolicy, conn ConnPolicy, jobs JobStorer) *Pooler {
 	return &Pooler{
 		bufferSize: bufferSize,
+		retry:      retry,
+		conn:       conn.withDefaults(),
+		jobs:       jobs,
+		commands:   make(chan command),
+		retryCh:    make(chan Task),
+		done:       make(chan struct{}),
 		queues:     map[int64]*Queue{},
-		addCh:      make(chan *Queue),
-		deleteCh:   make(chan int64),
-		tasksCh:    make(chan Task),
+		scheduler:  newScheduler(),
+		pools:      map[int64]pool.Pool{},
+		downSince:  map[int64]time.Time{},
+		canceled:   map[int64]bool{},
 	}
 }
 
-func (p *Pooler) Add(printers ...printer.Printer) {
-	for i := range printers {
-		p.addCh <- New(printers[i], p.bufferSize)
+// Add starts queues for printers and restores their unfinished jobs.
+func (p *Pooler) Add(ctx context.Context, printers ...printer.Printer) error {
+	return p.do(ctx, func(ctx context.Context) error {
+		for i := range printers {
+			pr := printers[i]
+			slog.Debug("pooler: got new queue to run", "printerID", pr.ID)
+			if _, ok := p.queues[pr.ID]; ok {
+				slog.Warn("adding existing queue, ignored", "printerID", pr.ID)
+				continue
+			}
+			p.add(ctx, pr)
+		}
+		return nil
+	})
+}
+
+// Update reconfigures the queue of the printer without losing pending jobs.
+// Jobs are rendered for the printer type, so the type cannot be changed
+// while the printer has pending or scheduled jobs, they have to be migrated
+// or finished first.
+func (p *Pooler) Update(ctx context.Context, pr printer.Printer) error {
+	return p.do(ctx, func(ctx context.Context) error {
+		slog.Debug("pooler: got printer to update", "printerID", pr.ID)
+		q, ok := p.queues[pr.ID]
+		if !ok {
+			p.add(ctx, pr)
+			return nil
+		}
+		if q.PrinterType() != pr.Type && p.scheduler.count()[pr.ID] > 0 {
+			return ErrJobsPending
+		}
+		return q.Reconfigure(pr)
+	})
+}
+
+// Delete stops the queue of the printer and cancels its unfinished jobs.
+func (p *Pooler) Delete(ctx context.Context, id int64) error {
+	return p.do(ctx, func(ctx context.Context) error {
+		slog.Debug("pooler: got command to stop queue", "printerID", id)
+		q, ok := p.queues[id]
+		if !ok {
+			slog.Warn("trying to delete queue that does not exist, ignored", "printerID", id)
+			return nil
+		}
+		delete(p.queues, id)
+		delete(p.downSince, id)
+		dropped := append(q.Drop(), p.scheduler.removePrinter(id)...)
+		for i := range dropped {
+			if p.reassign(ctx, dropped[i], q) {
+				continue
+			}
+			dropped[i].Job.Status = job.StatusCanceled
+			p.update(ctx, &dropped[i].Job)
+		}
+		return nil
+	})
+}
+
+// SetPools adds pools or replaces their members, jobs that are already
+// dispatched to removed members stay there.
+func (p *Pooler) SetPools(ctx context.Context, pools ...pool.Pool) error {
+	return p.do(ctx, func(ctx context.Context) error {
+		for _, pl := range pools {
+			slog.Debug("pooler: got pool", "poolID", pl.ID, "members", pl.PrinterIDs)
+			p.pools[pl.ID] = pl
+		}
+		return nil
+	})
+}
+
+// DeletePool forgets the pool, its pending jobs stay in the queues of
+// the members they are dispatched to.
+func (p *Pooler) DeletePool(ctx context.Context, id int64) error {
+	return p.do(ctx, func(ctx context.Context) error {
+		slog.Debug("pooler: got pool to delete", "poolID", id)
+		delete(p.pools, id)
+		return nil
+	})
+}
+
+// Enqueue stores the job and puts it into the queue of the job's printer,
+// job of a pool goes to the least loaded healthy member of the pool and
+// gets its printer ID. Job ID is set on success.
+func (p *Pooler) Enqueue(ctx context.Context, j *job.Job, document printer.Printable) error {
+	return p.do(ctx, func(ctx context.Context) error {
+		slog.Debug("pooler: got task to enqueue", "printerID", j.PrinterID, "poolID", j.PoolID)
+		var (
+			q   *Queue
+			err error
+		)
+		if j.PoolID != nil {
+			q, err = p.dispatch(*j.PoolID)
+			if err != nil {
+				return err
+			}
+			j.PrinterID = q.printerID
+		} else {
+			var ok bool
+			q, ok = p.queues[j.PrinterID]
+			if !ok {
+				return fmt.Errorf("%w: %d", ErrPrinterNotFound, j.PrinterID)
+			}
+		}
+		err = p.enqueue(ctx, q, j, document)
+		if err != nil {
+			slog.Warn("cannot enqueue task", "error", err)
+		}
+		return err
+	})
+}
+
+// Cancel stops the job in the queue of the job's printer.
+func (p *Pooler) Cancel(ctx context.Context, j job.Job) error {
+	return p.do(ctx, func(ctx context.Context) error {
+		slog.Debug("pooler: got job to cancel", "jobID", j.ID)
+		return p.cancel(ctx, j)
+	})
+}
+
+func (p *Pooler) cancel(ctx context.Context, j job.Job) error {
+	q, ok := p.queues[j.PrinterID]
+	if p.scheduler.remove(j.ID) || !ok {
+		// job is not in the running queue, so it is enough to mark it as canceled
+		j.Status = job.StatusCanceled
+		return p.jobs.Update(ctx, &j)
 	}
+	err := q.Cancel(ctx, j)
+	if errors.Is(err, errRetrying) {
+		// the queue is waiting for Run to take the task, so it is dropped
+		// when it is taken
+		p.canceled[j.ID] = true
+		j.Status = job.StatusCanceled
+		return p.jobs.Update(ctx, &j)
+	}
+	return err
 }
 
-func (p *Pooler) Delete(id int64) error {
-	p.deleteCh <- id
-	q, ok := p.queues[id]
-	if !ok {
-		slog.Warn("trying to delete queue that does not exist, ignored", "printerID", id)
+// Redrive puts the job from dead letters back to the queue of the job's printer.
+func (p *Pooler) Redrive(ctx context.Context, j job.Job) error {
+	return p.do(ctx, func(ctx context.Context) error {
+		slog.Debug("pooler: got job to redrive", "jobID", j.ID)
+		return p.redrive(ctx, j)
+	})
+}
+
+// Snapshot returns states of all queues ordered by printer ID.
+func (p *Pooler) Snapshot(ctx context.Context) ([]State, error) {
+	var states []State
+	err := p.do(ctx, func(ctx context.Context) error {
+		states = make([]State, 0, len(p.queues))
+		scheduled := p.scheduler.count()
+		for id, q := range p.queues {
+			st := q.State()
+			st.Scheduled = scheduled[id]
+			states = append(states, st)
+		}
 		return nil
+	})
+	slices.SortFunc(states, func(a, b State) int {
+		return cmp.Compare(a.PrinterID, b.PrinterID)
+	})
+	return states, err
+}
+
+// Unhealthy returns states of queues whose printers are not healthy,
+// ordered by printer ID.
+func (p *Pooler) Unhealthy(ctx context.Context) ([]State, error) {
+	states, err := p.Snapshot(ctx)
+	if err != nil {
+		return nil, err
 	}
-	delete(p.queues, id)
-	return q.Close()
+	return slices.DeleteFunc(states, func(st State) bool {
+		return st.Healthy
+	}), nil
 }
 
-func (p *Pooler) Enqueue(printerID int64, document printer.Printable, quantity int, timeout time.Duration) {
-	task := Task{
-		printerID: printerID,
-		Quantity:  quantity,
-		Timeout:   timeout,
-		Document:  document,
+// State returns state of the queue of the printer.
+func (p *Pooler) State(ctx context.Context, printerID int64) (State, error) {
+	var st State
+	err := p.do(ctx, func(ctx context.Context) error {
+		q, ok := p.queues[printerID]
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrPrinterNotFound, printerID)
+		}
+		st = q.State()
+		st.Scheduled = p.scheduler.count()[printerID]
+		return nil
+	})
+	return st, err
+}
+
+// Pending returns pending jobs of the printer in the order they are printed.
+func (p *Pooler) Pending(ctx context.Context, printerID int64) ([]job.Job, error) {
+	var pending []job.Job
+	err := p.do(ctx, func(ctx context.Context) error {
+		q, ok := p.queues[printerID]
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrPrinterNotFound, printerID)
+		}
+		pending = q.Pending()
+		return nil
+	})
+	return pending, err
+}
+
+// MoveToFront makes the pending job the next to print on its printer.
+func (p *Pooler) MoveToFront(ctx context.Context, printerID, jobID int64) error {
+	return p.do(ctx, func(ctx context.Context) error {
+		q, ok := p.queues[printerID]
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrPrinterNotFound, printerID)
+		}
+		if !q.MoveToFront(jobID) {
+			return fmt.Errorf("%w: %d", ErrJobNotQueued, jobID)
+		}
+		return nil
+	})
+}
+
+// Migrate moves pending and scheduled jobs of the printer to another
+// printer that accepts their rendered documents, all jobs are moved if no
+// job ID is given. The job that is printing at the moment is not moved.
+// Nothing is moved if any of the jobs is not found or the target queue
+// cannot take all of them.
+func (p *Pooler) Migrate(ctx context.Context, from, to int64, jobIDs ...int64) ([]job.Job, error) {
+	var moved []job.Job
+	err := p.do(ctx, func(ctx context.Context) error {
+		src, ok := p.queues[from]
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrPrinterNotFound, from)
+		}
+		dst, ok := p.queues[to]
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrPrinterNotFound, to)
+		}
+		if !dst.Accepts(src) {
+			return fmt.Errorf("%w: %d and %d", ErrIncompatible, to, from)
+		}
+		if from == to {
+			return nil
+		}
+		match := func(task Task) bool {
+			return len(jobIDs) == 0 || slices.Contains(jobIDs, task.Job.ID)
+		}
+
+		// pending tasks are checked as they are taken, since the queue may
+		// start printing any of them meanwhile
+		taken, err := src.TakeIf(match, func(taken []Task) error {
+			found := map[int64]bool{}
+			for _, task := range taken {
+				found[task.Job.ID] = true
+			}
+			for _, task := range p.scheduler.tasks {
+				if task.Job.PrinterID == from && match(task) {
+					found[task.Job.ID] = true
+				}
+			}
+			for _, id := range jobIDs {
+				if !found[id] {
+					return fmt.Errorf("%w: %d", ErrJobNotQueued, id)
+				}
+			}
+			if len(taken) > dst.Free() {
+				return ErrQueueFull
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		tasks := append(taken, p.scheduler.move(from, to, match)...)
+		moved = make([]job.Job, 0, len(tasks))
+		for _, task := range tasks {
+			slog.Info("pooler: job is migrated", "jobID", task.Job.ID, "from", from, "to", to)
+			task.Job.PrinterID = to
+			p.update(ctx, &task.Job)
+			if task.Job.Status != job.StatusScheduled {
+				// free room is checked above
+				dst.Enqueue(task)
+			}
+			moved = append(moved, task.Job)
+		}
+		return nil
+	})
+	return moved, err
+}
+
+// Pause stops sending jobs to the printer, the job that is printing at the
+// moment is held after the current copy.
+func (p *Pooler) Pause(ctx context.Context, printerID int64) (State, error) {
+	return p.control(ctx, printerID, (*Queue).Pause)
+}
+
+// Drain lets the job that is printing at the moment finish and pauses the
+// queue of the printer after it.
+func (p *Pooler) Drain(ctx context.Context, printerID int64) (State, error) {
+	return p.control(ctx, printerID, (*Queue).Drain)
+}
+
+// Resume continues sending jobs to the printer.
+func (p *Pooler) Resume(ctx context.Context, printerID int64) (State, error) {
+	return p.control(ctx, printerID, (*Queue).Resume)
+}
+
+// control applies fn to the queue of the printer and returns its state.
+func (p *Pooler) control(ctx context.Context, printerID int64, fn func(*Queue)) (State, error) {
+	var st State
+	err := p.do(ctx, func(ctx context.Context) error {
+		q, ok := p.queues[printerID]
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrPrinterNotFound, printerID)
+		}
+		fn(q)
+		st = q.State()
+		st.Scheduled = p.scheduler.count()[printerID]
+		return nil
+	})
+	return st, err
+}
+
+// PrinterStatus returns the last status reported by the printer.
+func (p *Pooler) PrinterStatus(ctx context.Context, printerID int64) (PrinterStatus, error) {
+	var st PrinterStatus
+	err := p.do(ctx, func(ctx context.Context) error {
+		q, ok := p.queues[printerID]
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrPrinterNotFound, printerID)
+		}
+		st = q.PrinterStatus()
+		return nil
+	})
+	return st, err
+}
+
+// do executes fn by the goroutine that runs the pooler and waits for the result.
+func (p *Pooler) do(ctx context.Context, fn func(context.Context) error) error {
+	cmd := command{
+		fn:   fn,
+		resp: make(chan error, 1),
+	}
+	select {
+	case p.commands <- cmd:
+	case <-p.done:
+		return ErrNotRunning
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-cmd.resp:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	p.tasksCh <- task
 }
 
 func (p *Pooler) Run(ctx context.Context) {
 	slog.Debug("pooler started")
+	defer close(p.done)
+	var failoverC <-chan time.Time
+	if p.conn.FailoverAfter > 0 {
+		ticker := time.NewTicker(p.conn.FailoverAfter / failoverChecks)
+		defer ticker.Stop()
+		failoverC = ticker.C
+	}
 	for {
 		select {
-		case q := <-p.addCh:
-			slog.Debug("pooler: got new queue to run", "printerID", q.printer.ID)
-			if _, ok := p.queues[q.printer.ID]; ok {
-				slog.Warn("adding existing queue, ignored", "printerID", q.printer.ID)
-				continue
+		case cmd := <-p.commands:
+			cmd.resp <- cmd.fn(ctx)
+		case now := <-p.scheduler.C():
+			p.release(ctx, now)
+		case now := <-failoverC:
+			p.failover(ctx, now)
+		case task := <-p.retryCh:
+			p.retryLater(ctx, task)
+		case <-ctx.Done():
+			for _, q := range p.queues {
+				q.Close()
 			}
-			p.queues[q.printer.ID] = q
-			p.wg.Add(1)
-			go func() {
-				defer p.wg.Done()
-				q.Process(ctx)
-			}()
-		case id := <-p.deleteCh:
-			slog.Debug("pooler: got command to stop queue", "printerID", id)
-			q, ok := p.queues[id]
-			if !ok {
-				slog.Warn("trying to delete queue that does not exist, ignored", "printerID", id)
-				continue
+			p.wg.Wait()
+			slog.Debug("pooler is done")
+			return
+		}
+	}
+}
+
+// enqueue renders the document for the printer of the queue and stores it
+// as a job before it gets to the queue, so the job survives restart.
+// Job that is not due yet goes to the scheduler.
+func (p *Pooler) enqueue(ctx context.Context, q *Queue, j *job.Job, document printer.Printable) error {
+	doc, err := q.Render(document)
+	if err != nil {
+		return err
+	}
+	j.Document = doc
+	if j.Retry.MaxAttempts == 0 {
+		j.Retry = p.retry
+	}
+	j.Status = job.StatusQueued
+	if !j.IsDue(time.Now()) {
+		j.Status = job.StatusScheduled
+	}
+	// queue is filled only by the pooler and the task that is printing keeps
+	// its slot when it is held, so the queue cannot become full after the check
+	if j.Status == job.StatusQueued && q.IsFull() {
+		return ErrQueueFull
+	}
+	if err := p.jobs.Store(ctx, j); err != nil {
+		return err
+	}
+	task := Task{Job: *j, Document: printer.Raw(doc)}
+	if j.Status == job.StatusScheduled {
+		p.scheduler.add(task)
+		return nil
+	}
+	if err := q.Enqueue(task); err != nil {
+		j.Status = job.StatusFailed
+		j.LastError = err.Error()
+		p.update(ctx, j)
+		return err
+	}
+	return nil
+}
+
+// retryLater schedules the failed task, the task canceled on its way is
+// dropped, since the queue has stored it as scheduled.
+func (p *Pooler) retryLater(ctx context.Context, task Task) {
+	if p.canceled[task.Job.ID] {
+		delete(p.canceled, task.Job.ID)
+		slog.Info("pooler: canceled job is not retried", "jobID", task.Job.ID)
+		task.Job.Status = job.StatusCanceled
+		p.update(ctx, &task.Job)
+		return
+	}
+	p.scheduler.add(task)
+}
+
+// release moves due tasks from the scheduler to the queues of their printers.
+func (p *Pooler) release(ctx context.Context, now time.Time) {
+	for _, task := range p.scheduler.due(now) {
+		slog.Debug("pooler: scheduled task is due", "jobID", task.Job.ID)
+		if task.Job.IsExpired(now) {
+			task.Job.Status = job.StatusExpired
+			p.update(ctx, &task.Job)
+			continue
+		}
+		q, ok := p.queues[task.Job.PrinterID]
+		if !ok {
+			task.Job.Status = job.StatusFailed
+			task.Job.LastError = fmt.Sprintf("%s: %d", ErrPrinterNotFound, task.Job.PrinterID)
+			p.update(ctx, &task.Job)
+			continue
+		}
+		if task.Job.PoolID != nil {
+			// job of a pool, e.g. retried after failure, goes to the member
+			// that is healthy at the moment
+			if member := p.pick(*task.Job.PoolID, q, 0, false); member != nil {
+				q = member
+				task.Job.PrinterID = q.printerID
 			}
-			delete(p.queues, id)
-			err := q.Close()
-			if err != nil {
-				slog.Error("closing queue", "printerID", q.printer.ID, "error", err)
+		}
+		// status is updated before the task gets to the queue, so it cannot
+		// overwrite the status set by the queue
+		task.Job.Status = job.StatusQueued
+		p.update(ctx, &task.Job)
+		if err := q.Enqueue(task); err != nil {
+			slog.Warn("pooler: cannot release scheduled task, postponed", "jobID", task.Job.ID, "error", err)
+			notBefore := now.Add(retryDelay)
+			task.Job.NotBefore = &notBefore
+			task.Job.Status = job.StatusScheduled
+			p.update(ctx, &task.Job)
+			p.scheduler.add(task)
+		}
+	}
+}
+
+// dispatch returns the queue of the pool member for a new job, member that
+// is not healthy gets the job only if there is no healthy one, so the job
+// is kept until failover moves it.
+func (p *Pooler) dispatch(poolID int64) (*Queue, error) {
+	pl, ok := p.pools[poolID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrPoolNotFound, poolID)
+	}
+	if q := p.pick(poolID, nil, 0, false); q != nil {
+		return q, nil
+	}
+	for _, id := range pl.PrinterIDs {
+		if _, ok := p.queues[id]; ok {
+			// members are running, but their queues are full
+			return nil, ErrQueueFull
+		}
+	}
+	return nil, fmt.Errorf("%w: pool %d has no running members", ErrPrinterNotFound, poolID)
+}
+
+// pick returns the least loaded member of the pool that is not full,
+// healthy members are preferred and members that are not healthy are
+// skipped if onlyHealthy is set. Paused member is not considered healthy.
+// If from is set, pending jobs are already rendered for its printer, so
+// only members that accept its documents are picked. The member with
+// exclude ID is skipped. Ties go to the member listed first.
+func (p *Pooler) pick(poolID int64, from *Queue, exclude int64, onlyHealthy bool) *Queue {
+	pl, ok := p.pools[poolID]
+	if !ok {
+		return nil
+	}
+	var (
+		best        *Queue
+		bestLoad    int
+		bestHealthy bool
+	)
+	for _, id := range pl.PrinterIDs {
+		q, ok := p.queues[id]
+		if !ok || id == exclude || q.IsFull() || (from != nil && !q.Accepts(from)) {
+			continue
+		}
+		healthy := q.IsAvailable()
+		if !healthy && onlyHealthy {
+			continue
+		}
+		load := q.Load()
+		if best == nil || (healthy && !bestHealthy) || (healthy == bestHealthy && load < bestLoad) {
+			best, bestLoad, bestHealthy = q, load, healthy
+		}
+	}
+	return best
+}
+
+// failover moves pending pool jobs away from printers that stay unhealthy
+// or paused longer than FailoverAfter to healthy members of the same pools
+// that accept their documents. Jobs that have no such member to go to stay
+// where they are.
+func (p *Pooler) failover(ctx context.Context, now time.Time) {
+	for id, q := range p.queues {
+		if !p.inPool(id) || q.IsAvailable() {
+			delete(p.downSince, id)
+			continue
+		}
+		since, ok := p.downSince[id]
+		if !ok {
+			p.downSince[id] = now
+			continue
+		}
+		if now.Sub(since) < p.conn.FailoverAfter {
+			continue
+		}
+		// members are picked before the tasks are taken, so tasks that have
+		// nowhere to go keep their place in the queue
+		candidates := p.failoverCandidates(id, q)
+		targets := map[int64]*Queue{}
+		taken := q.Take(func(task Task) bool {
+			if task.Job.PoolID == nil {
+				return false
 			}
-		case task := <-p.tasksCh:
-			slog.Debug("pooler: got task to enqueue", "task", task)
-			q, ok := p.queues[task.printerID]
-			if !ok {
-				slog.Warn("trying to enqueue document for printer that are not exists, ignoring", "printerID", task.printerID)
-				continue
+			var best *candidate
+			for _, c := range candidates[*task.Job.PoolID] {
+				if c.free > 0 && (best == nil || c.load < best.load) {
+					best = c
+				}
 			}
-			if err := q.Enqueue(task); err != nil {
-				slog.Warn("cannot enqueue task", "error", err)
+			if best == nil {
+				return false
 			}
-		case <-ctx.Done():
-			for _, q := range p.queues {
-				err := q.Close()
-				if err != nil {
-					slog.Error("closing queue", "printerID", q.printer.ID, "error", err)
+			best.load++
+			best.free--
+			targets[task.Job.ID] = best.q
+			return true
+		})
+		for _, task := range taken {
+			target := targets[task.Job.ID]
+			slog.Info("pooler: printer is down, job is moved", "jobID", task.Job.ID, "from", id, "to", target.printerID)
+			task.Job.PrinterID = target.printerID
+			p.update(ctx, &task.Job)
+			if err := target.Enqueue(task); err != nil {
+				// only the pooler adds tasks, so the counted room is not expected to run out
+				slog.Warn("pooler: cannot move job, put back", "jobID", task.Job.ID, "error", err)
+				task.Job.PrinterID = id
+				p.update(ctx, &task.Job)
+				q.restore(task)
+			}
+		}
+	}
+}
+
+// candidate is a pool member failover can move tasks to, load and free
+// room include the tasks already assigned to it.
+type candidate struct {
+	q          *Queue
+	load, free int
+}
+
+// failoverCandidates returns healthy members of pools of the printer that
+// accept documents of its queue, by pool in the order they are listed.
+// Member of several pools has the same candidate in each of them.
+func (p *Pooler) failoverCandidates(printerID int64, from *Queue) map[int64][]*candidate {
+	byPrinter := map[int64]*candidate{}
+	candidates := map[int64][]*candidate{}
+	for poolID, pl := range p.pools {
+		if !pl.Has(printerID) {
+			continue
+		}
+		for _, id := range pl.PrinterIDs {
+			c, ok := byPrinter[id]
+			if !ok {
+				q, ok := p.queues[id]
+				if !ok || id == printerID || !q.IsAvailable() || !q.Accepts(from) {
+					continue
 				}
+				c = &candidate{q: q, load: q.Load(), free: q.Free()}
+				byPrinter[id] = c
 			}
-			slog.Debug("pooler is done")
-			p.wg.Wait()
-			return
+			candidates[poolID] = append(candidates[poolID], c)
 		}
 	}
+	return candidates
+}
+
+// reassign moves the task of removed printer to another member of its pool
+// that accepts documents of the removed one, it returns false if the task
+// has no pool or no member can take it.
+func (p *Pooler) reassign(ctx context.Context, task Task, from *Queue) bool {
+	if task.Job.PoolID == nil {
+		return false
+	}
+	target := p.pick(*task.Job.PoolID, from, task.Job.PrinterID, false)
+	if target == nil {
+		return false
+	}
+	slog.Info("pooler: printer is removed, job is moved", "jobID", task.Job.ID, "from", task.Job.PrinterID, "to", target.printerID)
+	task.Job.PrinterID = target.printerID
+	p.update(ctx, &task.Job)
+	if task.Job.Status == job.StatusScheduled {
+		p.scheduler.add(task)
+		return true
+	}
+	return target.Enqueue(task) == nil
+}
+
+func (p *Pooler) inPool(printerID int64) bool {
+	for _, pl := range p.pools {
+		if pl.Has(printerID) {
+			return true
+		}
+	}
+	return false
+}
+
+// redrive resets attempts of the job and puts it to the queue again.
+func (p *Pooler) redrive(ctx context.Context, j job.Job) error {
+	q, ok := p.queues[j.PrinterID]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrPrinterNotFound, j.PrinterID)
+	}
+	if q.IsFull() {
+		return ErrQueueFull
+	}
+	j.Attempts = 0
+	j.NotBefore = nil
+	j.Status = job.StatusQueued
+	if err := p.jobs.Update(ctx, &j); err != nil {
+		return err
+	}
+	if err := q.Enqueue(Task{Job: j, Document: printer.Raw(j.Document)}); err != nil {
+		j.Status = job.StatusDeadLetter
+		p.update(ctx, &j)
+		return err
+	}
+	return nil
+}
+
+// add starts the queue for the printer and restores its unfinished jobs.
+func (p *Pooler) add(ctx context.Context, pr printer.Printer) {
+	q := New(pr, p.bufferSize, p.conn, p.jobs, p.retryCh)
+	p.queues[pr.ID] = q
+	q.start(ctx, &p.wg)
+	p.restore(ctx, q)
+}
+
+// restore enqueues jobs that were not finished before the last shutdown.
+func (p *Pooler) restore(ctx context.Context, q *Queue) {
+	jobs, err := p.jobs.ListUnfinished(ctx, q.printerID)
+	if err != nil {
+		slog.Error("pooler: cannot load unfinished jobs", "printerID", q.printerID, "error", err)
+		return
+	}
+	for _, j := range jobs {
+		slog.Info("pooler: restore unfinished job", "printerID", q.printerID, "jobID", j.ID)
+		task := Task{Job: j, Document: printer.Raw(j.Document)}
+		if j.Status == job.StatusScheduled {
+			// scheduler releases the job right away if it is already due
+			p.scheduler.add(task)
+			continue
+		}
+		q.restore(task)
+	}
+}
+
+func (p *Pooler) update(ctx context.Context, j *job.Job) {
+	if err := p.jobs.Update(ctx, j); err != nil {
+		slog.Error("pooler: cannot update job", "jobID", j.ID, "error", err)
+	}
 }
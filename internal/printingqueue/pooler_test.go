@@ -0,0 +1,649 @@
+package printingqueue
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"zhurd/internal/job"
+	"zhurd/internal/pool"
+	"zhurd/internal/printer"
+)
+
+func TestPoolerSnapshot(t *testing.T) {
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	p := NewPooler(8, job.RetryPolicy{MaxAttempts: 1}, ConnPolicy{}, jobs)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(stopped)
+	}()
+
+	printers := []printer.Printer{
+		{ID: 2, Type: "ZPL", Addr: "127.0.0.1:9100"},
+		{ID: 1, Type: "ZPL", Addr: "127.0.0.1:9101"},
+	}
+	if err := p.Add(ctx, printers...); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	states, err := p.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if len(states) != 2 || states[0].PrinterID != 1 || states[1].PrinterID != 2 {
+		t.Errorf("expected: queues of printers 1 and 2, got: %v\n", states)
+	}
+
+	notBefore := time.Now().Add(time.Hour)
+	j := job.New(1, 1, 0)
+	j.NotBefore = &notBefore
+	if err := p.Enqueue(ctx, &j, printer.Raw("^XA^XZ")); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	if err := p.Delete(ctx, 1); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	// jobs of the deleted printer are canceled
+	stored, err := jobs.Get(ctx, j.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.Status != job.StatusCanceled {
+		t.Errorf("expected: %v, got: %v\n", job.StatusCanceled, stored.Status)
+	}
+	// deleting the queue twice is ignored
+	if err := p.Delete(ctx, 1); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if _, err := p.State(ctx, 1); !errors.Is(err, ErrPrinterNotFound) {
+		t.Errorf("expected: %v, got: %v\n", ErrPrinterNotFound, err)
+	}
+
+	cancel()
+	<-stopped
+	if _, err := p.Snapshot(context.Background()); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected: %v, got: %v\n", ErrNotRunning, err)
+	}
+}
+
+func TestPoolerUnhealthy(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(io.Discard, c)
+		}
+	}()
+	// nobody listens on the port of the closed listener
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	closed.Close()
+
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	p := NewPooler(8, job.RetryPolicy{MaxAttempts: 1}, ConnPolicy{StatusInterval: -1}, jobs)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	go func() {
+		p.Run(ctx)
+		close(stopped)
+	}()
+
+	printers := []printer.Printer{
+		{ID: 1, Type: "ZPL", Addr: l.Addr().String()},
+		{ID: 2, Type: "ZPL", Addr: closed.Addr().String()},
+	}
+	if err := p.Add(ctx, printers...); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	j := job.New(1, 1, 0)
+	if err := p.Enqueue(ctx, &j, printer.Raw("^XA^XZ")); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	var states []State
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		states, err = p.Snapshot(ctx)
+		if err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		if states[0].JobsPrinted == 1 && states[1].LastError != "" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !states[0].Healthy || states[0].JobsPrinted != 1 || states[0].LastPrintAt == nil {
+		t.Errorf("expected healthy printer with one printed job, got: %+v\n", states[0])
+	}
+	if states[1].Healthy || states[1].LastError == "" || states[1].LastConnectAt == nil {
+		t.Errorf("expected unhealthy printer with connection error, got: %+v\n", states[1])
+	}
+
+	unhealthy, err := p.Unhealthy(ctx)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if len(unhealthy) != 1 || unhealthy[0].PrinterID != 2 {
+		t.Errorf("expected: only printer 2, got: %+v\n", unhealthy)
+	}
+}
+
+func TestPoolerPool(t *testing.T) {
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	p := NewPooler(8, job.RetryPolicy{MaxAttempts: 1}, ConnPolicy{FailoverAfter: time.Minute}, jobs)
+	// queues are not started, so tasks stay pending
+	for _, id := range []int64{1, 2, 3} {
+		p.queues[id] = New(printer.Printer{ID: id, Type: "ZPL", Addr: "127.0.0.1:9100"}, 8, p.conn, jobs, nil)
+	}
+	p.queues[3].printerType = "EPL"
+	p.pools[1] = pool.Pool{ID: 1, Name: "line", PrinterIDs: []int64{1, 2, 3}}
+	poolID := int64(1)
+	enqueue := func(printerID int64, pooled bool) job.Job {
+		j := job.New(printerID, 1, 0)
+		if pooled {
+			j.PoolID = &poolID
+		}
+		if err := jobs.Store(context.Background(), &j); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		if err := p.queues[printerID].Enqueue(Task{Job: j}); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		return j
+	}
+
+	enqueue(1, true)
+	enqueue(3, true)
+	if q := p.pick(1, nil, 0, false); q == nil || q.printerID != 2 {
+		t.Errorf("expected: least loaded printer 2, got: %v\n", q)
+	}
+	if q := p.pick(1, p.queues[3], 0, false); q == nil || q.printerID != 3 {
+		t.Errorf("expected: the only EPL printer 3, got: %v\n", q)
+	}
+
+	// loaded healthy member is preferred to unhealthy one
+	p.queues[2].setError(errors.New("connection refused"))
+	if q := p.pick(1, p.queues[2], 0, false); q == nil || q.printerID != 1 {
+		t.Errorf("expected: healthy printer 1, got: %v\n", q)
+	}
+
+	// pool jobs move away from the printer that stays unhealthy, others stay
+	pooled := enqueue(2, true)
+	direct := enqueue(2, false)
+	now := time.Now()
+	p.failover(context.Background(), now)
+	if depth := p.queues[2].Load(); depth != 2 {
+		t.Fatalf("expected: %d, got: %d\n", 2, depth)
+	}
+	p.failover(context.Background(), now.Add(time.Minute))
+	if depth := p.queues[2].Load(); depth != 1 {
+		t.Errorf("expected: %d, got: %d\n", 1, depth)
+	}
+	if depth := p.queues[1].Load(); depth != 2 {
+		t.Errorf("expected: %d, got: %d\n", 2, depth)
+	}
+	stored, err := jobs.Get(context.Background(), pooled.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.PrinterID != 1 {
+		t.Errorf("expected: %d, got: %d\n", 1, stored.PrinterID)
+	}
+	stored, err = jobs.Get(context.Background(), direct.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.PrinterID != 2 {
+		t.Errorf("expected: %d, got: %d\n", 2, stored.PrinterID)
+	}
+
+	// documents rendered for 300 dpi do not move to 203 dpi printer
+	p.queues[1].capabilities = &printer.Capabilities{Language: "ZPL", DPI: 203}
+	p.queues[2].capabilities = &printer.Capabilities{Language: "ZPL", DPI: 300}
+	stuck := enqueue(2, true)
+	later := enqueue(2, false)
+	p.queues[2].MoveToFront(stuck.ID)
+	p.failover(context.Background(), now.Add(2*time.Minute))
+	if depth := p.queues[2].Load(); depth != 3 {
+		t.Errorf("expected: %d, got: %d\n", 3, depth)
+	}
+	// jobs that have nowhere to go keep their place, moved to the front too
+	order := []int64{}
+	for _, j := range p.queues[2].Pending() {
+		order = append(order, j.ID)
+	}
+	if expected := []int64{stuck.ID, direct.ID, later.ID}; !slices.Equal(order, expected) {
+		t.Errorf("expected: %v, got: %v\n", expected, order)
+	}
+}
+
+func TestPoolerEnqueueToPool(t *testing.T) {
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	p := NewPooler(1, job.RetryPolicy{MaxAttempts: 1}, ConnPolicy{StatusInterval: -1}, jobs)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	go func() {
+		p.Run(ctx)
+		close(stopped)
+	}()
+
+	printers := []printer.Printer{
+		{ID: 1, Type: "ZPL", Addr: "127.0.0.1:9100"},
+		{ID: 2, Type: "ZPL", Addr: "127.0.0.1:9101"},
+	}
+	if err := p.Add(ctx, printers...); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if err := p.SetPools(ctx, pool.Pool{ID: 1, Name: "line", PrinterIDs: []int64{2, 1}}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	poolID := int64(1)
+	notBefore := time.Now().Add(time.Hour)
+	j := job.New(0, 1, 0)
+	j.PoolID = &poolID
+	j.NotBefore = &notBefore
+	if err := p.Enqueue(ctx, &j, printer.Raw("^XA^XZ")); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	// both members are idle, so the first listed gets the job
+	if j.PrinterID != 2 {
+		t.Errorf("expected: %d, got: %d\n", 2, j.PrinterID)
+	}
+
+	unknown := int64(42)
+	j = job.New(0, 1, 0)
+	j.PoolID = &unknown
+	if err := p.Enqueue(ctx, &j, printer.Raw("^XA^XZ")); !errors.Is(err, ErrPoolNotFound) {
+		t.Errorf("expected: %v, got: %v\n", ErrPoolNotFound, err)
+	}
+
+	if err := p.DeletePool(ctx, poolID); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	j = job.New(0, 1, 0)
+	j.PoolID = &poolID
+	if err := p.Enqueue(ctx, &j, printer.Raw("^XA^XZ")); !errors.Is(err, ErrPoolNotFound) {
+		t.Errorf("expected: %v, got: %v\n", ErrPoolNotFound, err)
+	}
+}
+
+func TestPoolerMigrate(t *testing.T) {
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	p := NewPooler(4, job.RetryPolicy{MaxAttempts: 1}, ConnPolicy{StatusInterval: -1}, jobs)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	go func() {
+		p.Run(ctx)
+		close(stopped)
+	}()
+
+	printers := []printer.Printer{
+		{ID: 1, Type: "ZPL", Addr: "127.0.0.1:9100", Capabilities: &printer.Capabilities{DPI: 203}},
+		{ID: 2, Type: "ZPL", Addr: "127.0.0.1:9101", Capabilities: &printer.Capabilities{DPI: 203}},
+		{ID: 3, Type: "EPL", Addr: "127.0.0.1:9102"},
+		{ID: 4, Type: "ZPL", Addr: "127.0.0.1:9103", Capabilities: &printer.Capabilities{DPI: 300}},
+	}
+	if err := p.Add(ctx, printers...); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	// paused queues keep jobs pending
+	for _, id := range []int64{1, 2} {
+		if _, err := p.Pause(ctx, id); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+	enqueue := func(notBefore *time.Time) int64 {
+		j := job.New(1, 1, 0)
+		j.NotBefore = notBefore
+		if err := p.Enqueue(ctx, &j, printer.Raw("^XA^XZ")); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		return j.ID
+	}
+	a, b, c := enqueue(nil), enqueue(nil), enqueue(nil)
+	notBefore := time.Now().Add(time.Hour)
+	scheduled := enqueue(&notBefore)
+	pendingIDs := func(printerID int64) []int64 {
+		pending, err := p.Pending(ctx, printerID)
+		if err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		ids := []int64{}
+		for _, j := range pending {
+			ids = append(ids, j.ID)
+		}
+		return ids
+	}
+
+	if err := p.MoveToFront(ctx, 1, c); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if ids := pendingIDs(1); !slices.Equal(ids, []int64{c, a, b}) {
+		t.Errorf("expected: %v, got: %v\n", []int64{c, a, b}, ids)
+	}
+	if err := p.MoveToFront(ctx, 1, scheduled); !errors.Is(err, ErrJobNotQueued) {
+		t.Errorf("expected: %v, got: %v\n", ErrJobNotQueued, err)
+	}
+
+	ucs := []struct {
+		name     string
+		to       int64
+		jobIDs   []int64
+		expected error
+	}{
+		{name: "unknown printer", to: 42, expected: ErrPrinterNotFound},
+		{name: "other type", to: 3, expected: ErrIncompatible},
+		{name: "other resolution", to: 4, expected: ErrIncompatible},
+		{name: "unknown job", to: 2, jobIDs: []int64{b, 42}, expected: ErrJobNotQueued},
+	}
+	for _, uc := range ucs {
+		t.Run(uc.name, func(t *testing.T) {
+			if _, err := p.Migrate(ctx, 1, uc.to, uc.jobIDs...); !errors.Is(err, uc.expected) {
+				t.Errorf("expected: %v, got: %v\n", uc.expected, err)
+			}
+		})
+	}
+	if ids := pendingIDs(1); len(ids) != 3 {
+		t.Errorf("expected: %d, got: %d\n", 3, len(ids))
+	}
+
+	moved, err := p.Migrate(ctx, 1, 2, b)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if len(moved) != 1 || moved[0].ID != b || moved[0].PrinterID != 2 {
+		t.Errorf("expected: job %d on printer 2, got: %v\n", b, moved)
+	}
+
+	// the rest moves in print order, the scheduled job moves too
+	if _, err := p.Migrate(ctx, 1, 2); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if ids := pendingIDs(1); len(ids) != 0 {
+		t.Errorf("expected: empty queue, got: %v\n", ids)
+	}
+	if ids := pendingIDs(2); !slices.Equal(ids, []int64{b, c, a}) {
+		t.Errorf("expected: %v, got: %v\n", []int64{b, c, a}, ids)
+	}
+	stored, err := jobs.Get(ctx, scheduled)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.PrinterID != 2 {
+		t.Errorf("expected: %d, got: %d\n", 2, stored.PrinterID)
+	}
+
+	// printer 2 has room for one job only
+	enqueue(nil)
+	enqueue(nil)
+	if _, err := p.Migrate(ctx, 1, 2); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected: %v, got: %v\n", ErrQueueFull, err)
+	}
+}
+
+func TestPoolerCancelRetrying(t *testing.T) {
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	p := NewPooler(8, job.RetryPolicy{MaxAttempts: 3}, ConnPolicy{}, jobs)
+	q := New(printer.Printer{ID: 1, Type: "ZPL", Addr: "127.0.0.1:9100"}, 8, p.conn, jobs, p.retryCh)
+	p.queues[1] = q
+	ctx := context.Background()
+
+	j := job.New(1, 1, 0)
+	j.Retry = p.retry
+	if err := jobs.Store(ctx, &j); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if err := q.Enqueue(Task{Job: j}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	task, _ := q.next()
+
+	// failed task waits for Run to take it, it is neither current nor pending
+	finished := make(chan struct{})
+	go func() {
+		q.finish(ctx, &task, errors.New("connection refused"))
+		close(finished)
+	}()
+	waitFor(t, 5*time.Second, func() bool {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return q.retrying == j.ID
+	})
+	if err := p.cancel(ctx, j); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	p.retryLater(ctx, <-p.retryCh)
+	<-finished
+
+	if n := len(p.scheduler.tasks); n != 0 {
+		t.Errorf("expected canceled job not to be scheduled, got: %d\n", n)
+	}
+	stored, err := jobs.Get(ctx, j.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.Status != job.StatusCanceled {
+		t.Errorf("expected: %v, got: %v\n", job.StatusCanceled, stored.Status)
+	}
+
+	// the job is not in the queue anymore
+	if err := p.cancel(ctx, j); !errors.Is(err, ErrJobNotQueued) {
+		t.Errorf("expected: %v, got: %v\n", ErrJobNotQueued, err)
+	}
+}
+
+func TestPoolerRestore(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	sp := &statusPrinter{}
+	go sp.serve(l)
+
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// jobs left by the previous run: one was interrupted after two of three
+	// copies, one waits for its time, one is done, one belongs to another
+	// printer
+	notBefore := time.Now().Add(time.Hour)
+	interrupted := job.New(1, 3, 0)
+	interrupted.Status = job.StatusPrinting
+	interrupted.Printed = 2
+	interrupted.Retry = job.RetryPolicy{MaxAttempts: 1}
+	interrupted.Document = []byte("^XA^XZ")
+	scheduled := job.New(1, 1, 0)
+	scheduled.Status = job.StatusScheduled
+	scheduled.NotBefore = &notBefore
+	scheduled.Document = []byte("^XA^XZ")
+	done := job.New(1, 1, 0)
+	done.Status = job.StatusDone
+	done.Printed = 1
+	done.Document = []byte("^XA^XZ")
+	other := job.New(2, 1, 0)
+	other.Document = []byte("^XA^XZ")
+	for _, j := range []*job.Job{&interrupted, &scheduled, &done, &other} {
+		if err := jobs.Store(ctx, j); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+
+	p := NewPooler(8, job.RetryPolicy{MaxAttempts: 1}, ConnPolicy{StatusInterval: -1}, jobs)
+	stopped := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(stopped)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	// EPL printer gets the document per copy, so resumed copies are counted
+	if err := p.Add(ctx, printer.Printer{ID: 1, Type: "EPL", Addr: l.Addr().String()}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		stored, err := jobs.Get(ctx, interrupted.ID)
+		return err == nil && stored.Status == job.StatusDone
+	})
+	stored, err := jobs.Get(ctx, interrupted.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.Printed != 3 {
+		t.Errorf("expected: %d, got: %d\n", 3, stored.Printed)
+	}
+	// only the copy left is printed, the done job is not printed again
+	if n := sp.printed.Load(); n != 1 {
+		t.Errorf("expected: %d, got: %d\n", 1, n)
+	}
+
+	st, err := p.State(ctx, 1)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if st.Scheduled != 1 || st.Depth != 0 {
+		t.Errorf("expected scheduled job only, got: %+v\n", st)
+	}
+	stored, err = jobs.Get(ctx, scheduled.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.Status != job.StatusScheduled {
+		t.Errorf("expected: %v, got: %v\n", job.StatusScheduled, stored.Status)
+	}
+	stored, err = jobs.Get(ctx, other.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.Status != job.StatusQueued {
+		t.Errorf("expected: %v, got: %v\n", job.StatusQueued, stored.Status)
+	}
+}
+
+func TestPoolerRestoreOverflow(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	// printer without paper holds the restored jobs in the queue
+	sp := &statusPrinter{}
+	go sp.serve(l)
+
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var restored []job.Job
+	for range 4 {
+		j := job.New(1, 1, 0)
+		j.Retry = job.RetryPolicy{MaxAttempts: 1}
+		j.Document = []byte("^XA^XZ")
+		if err := jobs.Store(ctx, &j); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		restored = append(restored, j)
+	}
+
+	// the queue holds two jobs, but all persisted jobs are restored
+	p := NewPooler(2, job.RetryPolicy{MaxAttempts: 1}, ConnPolicy{StatusInterval: 50 * time.Millisecond}, jobs)
+	stopped := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(stopped)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	if err := p.Add(ctx, printer.Printer{ID: 1, Type: "ZPL", Addr: l.Addr().String()}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	st, err := p.State(ctx, 1)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if st.Depth != 4 {
+		t.Errorf("expected: %d, got: %d\n", 4, st.Depth)
+	}
+
+	// new jobs wait until the restored ones drain
+	j := job.New(1, 1, 0)
+	if err := p.Enqueue(ctx, &j, printer.Raw("^XA^XZ")); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected: %v, got: %v\n", ErrQueueFull, err)
+	}
+	// restored job over the size of the queue can be canceled
+	if err := p.Cancel(ctx, restored[3]); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	sp.loaded.Store(true)
+	waitFor(t, 5*time.Second, func() bool {
+		stored, err := jobs.Get(ctx, restored[2].ID)
+		return err == nil && stored.Status == job.StatusDone
+	})
+	for i, status := range []job.Status{job.StatusDone, job.StatusDone, job.StatusDone, job.StatusCanceled} {
+		stored, err := jobs.Get(ctx, restored[i].ID)
+		if err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		if stored.Status != status {
+			t.Errorf("job %d: expected: %v, got: %v\n", stored.ID, status, stored.Status)
+		}
+	}
+}
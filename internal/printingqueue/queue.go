@@ -1,79 +1,1164 @@
 package printingqueue
 
 import (
+	"container/heap"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
+	"os"
+	"sync"
 	"time"
 
+	"zhurd/internal/job"
 	"zhurd/internal/printer"
 )
 
+var (
+	ErrQueueFull       = errors.New("queue is full")
+	ErrPrinterNotFound = errors.New("printer has no queue")
+	ErrPoolNotFound    = errors.New("pool not found")
+	ErrJobNotQueued    = errors.New("job is not pending in the queue")
+	ErrIncompatible    = errors.New("printer does not accept documents rendered for the other one")
+	ErrNotRunning      = errors.New("pooler is not running")
+	ErrJobsPending     = errors.New("printer type cannot be changed while it has pending jobs")
+
+	errExpired     = errors.New("job is expired")
+	errUnconfirmed = errors.New("labels are not confirmed by printer")
+	// errRetrying is returned by Cancel for the failed job that is handed to
+	// the pooler to retry, the pooler drops it when it gets the task.
+	errRetrying = errors.New("job is handed to retry")
+)
+
+type JobStorer interface {
+	Store(context.Context, *job.Job) error
+	Update(context.Context, *job.Job) error
+	ListUnfinished(context.Context, int64) ([]job.Job, error)
+}
+
 type Task struct {
-	printerID int64
-	Quantity  int
-	Timeout   time.Duration
-	Document  printer.Printable
+	Job      job.Job
+	Document printer.Printable
+}
+
+// Dispatch tells whether the queue sends tasks to the printer. Paused and
+// draining queues keep accepting tasks and hold them until resumed.
+type Dispatch string
+
+const (
+	DispatchRunning Dispatch = "running"
+	// the current job is finished, then the queue is paused
+	DispatchDraining Dispatch = "draining"
+	// the current job is held after the current copy, or after the current
+	// batch of copies if the printer makes them itself
+	DispatchPaused Dispatch = "paused"
+)
+
+// State is a snapshot of the queue and health of its printer since start.
+type State struct {
+	PrinterID int64    `json:"printer_id"`
+	Connected bool     `json:"connected"`
+	Ready     bool     `json:"ready"`
+	Dispatch  Dispatch `json:"dispatch"`
+	// Healthy is false while the printer is not ready or the last attempt
+	// to connect or print failed.
+	Healthy       bool       `json:"healthy"`
+	LastConnectAt *time.Time `json:"last_connect_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastPrintAt   *time.Time `json:"last_print_at,omitempty"`
+	JobsPrinted   int        `json:"jobs_printed"`
+	Depth         int        `json:"depth"`
+	Scheduled     int        `json:"scheduled"`
+	Current       *job.Job   `json:"current,omitempty"`
+}
+
+// PrinterStatus is the last status reported by the printer, printer that
+// is not polled yet is considered ready.
+type PrinterStatus struct {
+	PrinterID int64           `json:"printer_id"`
+	Ready     bool            `json:"ready"`
+	Status    *printer.Status `json:"status,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	CheckedAt *time.Time      `json:"checked_at,omitempty"`
+}
+
+const (
+	// statusTimeout limits the time of status query.
+	statusTimeout = 3 * time.Second
+	// maxStatusTimeouts is the number of queries in a row without response,
+	// after which printer is considered not supporting status at all.
+	maxStatusTimeouts = 3
+	// verifyInterval is the period of reading label counter while the
+	// queue waits for labels to come out.
+	verifyInterval = 500 * time.Millisecond
+	// copiesBatch is the largest number of copies the printer makes from a
+	// single document, so pause and cancel stop the job between batches.
+	copiesBatch = 10
+)
+
+// ConnPolicy configures connections of queues to printers, zero values
+// are replaced by DefaultConnPolicy.
+type ConnPolicy struct {
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	// Backoff is the delay before reconnect after the first failure, it
+	// doubles with every next failure up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// StatusInterval is the period of status polling, negative value
+	// disables polling and flow control.
+	StatusInterval time.Duration
+	// FlowInterval is the period of status polling while the queue waits
+	// for the printer buffer, negative value disables flow control, so
+	// copies are paced by the timeout of the job.
+	FlowInterval time.Duration
+	// MaxBuffered is the number of formats the printer may hold, printing
+	// or waiting in its receive buffer, before the next one is sent.
+	MaxBuffered int
+	// VerifyTimeout limits the time the queue waits for the label counter
+	// of verified printer to confirm the job.
+	VerifyTimeout time.Duration
+	// FailoverAfter is the time the printer of a pool stays unhealthy
+	// before its pending pool jobs move to other members, negative value
+	// disables failover.
+	FailoverAfter time.Duration
 }
 
+var DefaultConnPolicy = ConnPolicy{
+	DialTimeout:    5 * time.Second,
+	WriteTimeout:   30 * time.Second,
+	Backoff:        time.Second,
+	MaxBackoff:     time.Minute,
+	StatusInterval: 10 * time.Second,
+	FlowInterval:   250 * time.Millisecond,
+	MaxBuffered:    2,
+	VerifyTimeout:  30 * time.Second,
+	FailoverAfter:  30 * time.Second,
+}
+
+func (c ConnPolicy) withDefaults() ConnPolicy {
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = DefaultConnPolicy.DialTimeout
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = DefaultConnPolicy.WriteTimeout
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = DefaultConnPolicy.Backoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = DefaultConnPolicy.MaxBackoff
+	}
+	if c.StatusInterval == 0 {
+		c.StatusInterval = DefaultConnPolicy.StatusInterval
+	}
+	if c.FlowInterval == 0 {
+		c.FlowInterval = DefaultConnPolicy.FlowInterval
+	}
+	if c.MaxBuffered <= 0 {
+		c.MaxBuffered = DefaultConnPolicy.MaxBuffered
+	}
+	if c.VerifyTimeout <= 0 {
+		c.VerifyTimeout = DefaultConnPolicy.VerifyTimeout
+	}
+	if c.FailoverAfter == 0 {
+		c.FailoverAfter = DefaultConnPolicy.FailoverAfter
+	}
+	return c
+}
+
+// delay returns the delay before reconnect after the given number of
+// failures, jitter keeps queues of the same failed network from
+// reconnecting at once.
+func (c ConnPolicy) delay(failures int) time.Duration {
+	delay := job.RetryPolicy{Backoff: c.Backoff, MaxBackoff: c.MaxBackoff}.Delay(failures)
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int64N(half+1))
+}
+
+// Queue prints tasks on the printer one by one. The printer and the state
+// of reconnect are used only by the goroutine that runs Process, other
+// fields are guarded by mutex.
 type Queue struct {
-	printer printer.Printer
-	q       chan Task
-	cancel  context.CancelFunc
+	printerID int64
+	printer   printer.Printer
+	conn      ConnPolicy
+	jobs      JobStorer
+	size      int
+	notify    chan struct{}
+	retry     chan<- Task
+	cancel    context.CancelFunc
+	// failures is the number of connection failures in a row
+	failures    int
+	reconnectAt time.Time
+	polledAt    time.Time
+	// statusUnsupported is set if the transport or the printer cannot
+	// report status
+	statusUnsupported bool
+	statusTimeouts    int
+
+	mu           sync.Mutex
+	printerType  string
+	capabilities *printer.Capabilities
+	tasks        tasks
+	seq          int64
+	// frontSeq decreases with every task moved to the front
+	frontSeq int64
+	current  *job.Job
+	canceled bool
+	// retrying is the ID of the failed job that is handed to retry channel
+	retrying  int64
+	connected bool
+	dispatch  Dispatch
+	// config is applied by Process before the next task
+	config *printer.Printer
+	status PrinterStatus
+	health health
 }
 
-func New(printer printer.Printer, size int) *Queue {
+// health is collected by Process and reported in the queue state.
+type health struct {
+	lastConnectAt time.Time
+	lastError     string
+	lastPrintAt   time.Time
+	jobsPrinted   int
+}
+
+// New creates queue for the printer, failed tasks that have attempts left
+// are sent to retry channel to be scheduled again.
+func New(printer printer.Printer, size int, conn ConnPolicy, jobs JobStorer, retry chan<- Task) *Queue {
 	return &Queue{
-		printer: printer,
-		q:       make(chan Task, size),
-		cancel:  func() {}, // noop cancel func
+		printerID:    printer.ID,
+		printer:      printer,
+		conn:         conn.withDefaults(),
+		printerType:  printer.Type,
+		capabilities: printer.Capabilities,
+		jobs:         jobs,
+		size:         size,
+		notify:       make(chan struct{}, 1),
+		retry:        retry,
+		cancel:       func() {}, // noop cancel func
+		dispatch:     DispatchRunning,
+		status:       PrinterStatus{PrinterID: printer.ID, Ready: true},
 	}
 }
 
+func (q *Queue) PrinterType() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.printerType
+}
+
+// Accepts reports whether documents rendered for the printer of the other
+// queue print the same way on the printer of this queue. The printers must
+// be of the same type. Documents rendered by capabilities need the same
+// resolution and print width and all options of the other printer, since
+// the chosen template may depend on any of them.
+func (q *Queue) Accepts(other *Queue) bool {
+	pType, c := q.target()
+	otherType, otherC := other.target()
+	if pType != otherType {
+		return false
+	}
+	if otherC == nil {
+		// documents are rendered without capabilities
+		return true
+	}
+	if c == nil || c.DPI != otherC.DPI || c.PrintWidth != otherC.PrintWidth {
+		return false
+	}
+	for _, option := range otherC.Options {
+		if !c.Has(option) {
+			return false
+		}
+	}
+	return true
+}
+
+// target returns the type and capabilities documents are rendered for.
+func (q *Queue) target() (string, *printer.Capabilities) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.printerType, q.capabilities
+}
+
+// Render renders the document for the type and capabilities of the printer.
+func (q *Queue) Render(document printer.Printable) ([]byte, error) {
+	pType, capabilities := q.target()
+	return printer.Render(document, pType, capabilities)
+}
+
 func (q *Queue) Enqueue(task Task) error {
-	slog.Debug("queue: got task to enqueue", "task", task)
+	slog.Debug("queue: got task to enqueue", "jobID", task.Job.ID, "priority", task.Job.Priority)
+	q.mu.Lock()
+	if q.load() >= q.size {
+		q.mu.Unlock()
+		return ErrQueueFull
+	}
+	q.push(task)
+	q.mu.Unlock()
+
+	q.wake()
+	return nil
+}
+
+// restore enqueues the persisted task even if the queue is full, the job
+// is accepted already, so it is not left in storage without a queue. The
+// queue takes no new tasks until it drains below its size.
+func (q *Queue) restore(task Task) {
+	q.mu.Lock()
+	q.push(task)
+	q.mu.Unlock()
+
+	q.wake()
+}
+
+// push adds the task to the heap, q.mu must be held.
+func (q *Queue) push(task Task) {
+	q.seq++
+	heap.Push(&q.tasks, queuedTask{Task: task, seq: q.seq})
+}
+
+// IsFull reports whether the queue cannot take a task, the task that is
+// printing keeps its slot, so it can be held without overflowing the queue.
+func (q *Queue) IsFull() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.load() >= q.size
+}
+
+func (q *Queue) State() State {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	st := State{
+		PrinterID:   q.printerID,
+		Connected:   q.connected,
+		Ready:       q.status.Ready,
+		Dispatch:    q.dispatch,
+		Healthy:     q.healthy(),
+		LastError:   q.health.lastError,
+		JobsPrinted: q.health.jobsPrinted,
+		Depth:       len(q.tasks),
+	}
+	if !q.health.lastConnectAt.IsZero() {
+		at := q.health.lastConnectAt
+		st.LastConnectAt = &at
+	}
+	if !q.health.lastPrintAt.IsZero() {
+		at := q.health.lastPrintAt
+		st.LastPrintAt = &at
+	}
+	if q.current != nil {
+		current := *q.current
+		st.Current = &current
+	}
+	return st
+}
+
+// healthy reports whether the printer is ready and the last attempt to
+// connect or print succeeded, q.mu must be held.
+func (q *Queue) healthy() bool {
+	return q.status.Ready && q.health.lastError == ""
+}
+
+// IsAvailable reports whether the printer is healthy and the queue sends
+// tasks to it, so it may take jobs of pools.
+func (q *Queue) IsAvailable() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.healthy() && q.dispatch == DispatchRunning
+}
+
+// Pause stops sending tasks to the printer, the job that is printing at the
+// moment is held after the current copy and continues from the next copy
+// when the queue is resumed.
+func (q *Queue) Pause() {
+	q.setDispatch(DispatchPaused)
+}
+
+// Drain lets the job that is printing at the moment finish and pauses the
+// queue after it. Paused queue stays paused.
+func (q *Queue) Drain() {
+	q.mu.Lock()
+	if q.dispatch == DispatchRunning {
+		q.dispatch = DispatchDraining
+	}
+	q.mu.Unlock()
+	q.wake()
+}
+
+// Resume continues sending tasks to the printer.
+func (q *Queue) Resume() {
+	q.setDispatch(DispatchRunning)
+}
+
+func (q *Queue) setDispatch(dispatch Dispatch) {
+	q.mu.Lock()
+	q.dispatch = dispatch
+	q.mu.Unlock()
+	q.wake()
+}
+
+// wake interrupts waiting of Process, so it checks the queue again.
+func (q *Queue) wake() {
 	select {
-	case q.q <- task:
+	case q.notify <- struct{}{}:
 	default:
-		return fmt.Errorf("cannot enqueue task, queue already full")
 	}
+}
+
+// Load returns the number of pending tasks and the task that is printing.
+func (q *Queue) Load() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.load()
+}
+
+// load is Load with mu held.
+func (q *Queue) load() int {
+	load := len(q.tasks)
+	if q.current != nil {
+		load++
+	}
+	return load
+}
+
+// Take removes pending tasks that match and returns them in the order
+// they would be printed.
+func (q *Queue) Take(match func(Task) bool) []Task {
+	taken, _ := q.TakeIf(match, nil)
+	return taken
+}
+
+// TakeIf is Take that removes the tasks only if check returns no error, so
+// none of them starts printing between the check and the removal. Check is
+// called with the queue locked, so it must not use the queue.
+func (q *Queue) TakeIf(match func(Task) bool, check func([]Task) error) ([]Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var taken []Task
+	kept := tasks{}
+	for _, t := range q.tasks.ordered() {
+		if match(t.Task) {
+			taken = append(taken, t.Task)
+			continue
+		}
+		kept = append(kept, t)
+	}
+	if check != nil {
+		if err := check(taken); err != nil {
+			return nil, err
+		}
+	}
+	q.tasks = kept
+	heap.Init(&q.tasks)
+	return taken, nil
+}
+
+// Pending returns jobs of pending tasks in the order they are printed,
+// the job that is printing at the moment is not pending.
+func (q *Queue) Pending() []job.Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending := make([]job.Job, 0, len(q.tasks))
+	for _, t := range q.tasks.ordered() {
+		pending = append(pending, t.Job)
+	}
+	return pending
+}
+
+// MoveToFront makes the pending task of the job the next to print, it
+// returns false if the job is not pending.
+func (q *Queue) MoveToFront(jobID int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range q.tasks {
+		if q.tasks[i].Job.ID == jobID {
+			q.frontSeq--
+			q.tasks[i].front = true
+			q.tasks[i].seq = q.frontSeq
+			heap.Fix(&q.tasks, i)
+			return true
+		}
+	}
+	return false
+}
+
+// Free returns the number of tasks the queue can take.
+func (q *Queue) Free() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return max(q.size-q.load(), 0)
+}
+
+// PrinterStatus returns the last status reported by the printer.
+func (q *Queue) PrinterStatus() PrinterStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	st := q.status
+	if st.Status != nil {
+		status := *st.Status
+		st.Status = &status
+	}
+	return st
+}
+
+// Cancel removes pending job from the queue, the job that is printing at the
+// moment stops after the current copy. It returns ErrJobNotQueued if the job
+// is neither pending nor printing.
+func (q *Queue) Cancel(ctx context.Context, j job.Job) error {
+	q.mu.Lock()
+	if q.current != nil && q.current.ID == j.ID {
+		q.canceled = true
+		q.mu.Unlock()
+		return nil
+	}
+	if q.retrying == j.ID {
+		q.mu.Unlock()
+		return errRetrying
+	}
+	removed := q.tasks.remove(j.ID)
+	q.mu.Unlock()
+	if !removed {
+		return fmt.Errorf("%w: %d", ErrJobNotQueued, j.ID)
+	}
+	j.Status = job.StatusCanceled
+	return q.jobs.Update(ctx, &j)
+}
+
+// start runs Process in a new goroutine, the queue can be stopped by Close.
+func (q *Queue) start(ctx context.Context, wg *sync.WaitGroup) {
+	ctx, q.cancel = context.WithCancel(ctx)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.Process(ctx)
+	}()
+}
+
+// Close stops processing of the queue, the connection to the printer is
+// closed by Process when it returns.
+func (q *Queue) Close() {
+	q.cancel()
+}
+
+// Drop stops processing of the queue and returns pending tasks, the job
+// that is printing at the moment is canceled after the current copy.
+func (q *Queue) Drop() []Task {
+	q.mu.Lock()
+	dropped := q.drain()
+	if q.current != nil {
+		q.canceled = true
+	}
+	q.mu.Unlock()
+	q.cancel()
+	return dropped
+}
+
+// Reconfigure makes the queue print to the updated printer, the connection
+// is reopened before the next task. Pending tasks are rendered for the printer
+// type, so the type cannot be changed while there are any, ErrJobsPending is
+// returned then. Tasks are not rendered again when capabilities change, they
+// are printed as they were rendered for the previous ones.
+func (q *Queue) Reconfigure(p printer.Printer) error {
+	q.mu.Lock()
+	if p.Type != q.printerType && q.load() > 0 {
+		q.mu.Unlock()
+		return ErrJobsPending
+	}
+	q.printerType = p.Type
+	q.capabilities = p.Capabilities
+	q.config = &p
+	q.mu.Unlock()
+
+	q.wake()
 	return nil
 }
 
-func (q *Queue) Close() error {
-	defer close(q.q)
-	defer q.cancel()
-	return q.printer.Close()
+// drain removes all pending tasks, q.mu must be held.
+func (q *Queue) drain() []Task {
+	dropped := make([]Task, 0, len(q.tasks))
+	for _, t := range q.tasks {
+		dropped = append(dropped, t.Task)
+	}
+	q.tasks = nil
+	return dropped
 }
 
-func (q *Queue) Process(ctx context.Context) error {
-	slog.Debug("start queue processing for printer", "printerID", q.printer.ID)
-	ctx, cancel := context.WithCancel(ctx)
-	q.cancel = cancel
-	if err := q.printer.Connect(); err != nil {
-		slog.Error("cannot connect to printer", "printerID", q.printer.ID, "addr", q.printer.Addr, "error", err)
+// Process prints tasks until ctx is done.
+func (q *Queue) Process(ctx context.Context) {
+	slog.Debug("start queue processing for printer", "printerID", q.printerID)
+	defer func() {
+		if err := q.printer.Close(); err != nil {
+			slog.Error("closing printer connection", "printerID", q.printerID, "error", err)
+		}
+		q.setConnected()
+		slog.Debug("processing queue for printer is done", "printerID", q.printerID)
+	}()
+
+	if !q.printer.IsPerJob() {
+		q.connect(ctx)
 	}
 	for {
-		select {
-		case task := <-q.q:
-			slog.Debug("queue: got task to process", "task", task)
-			if !q.printer.IsConnected() {
-				slog.Debug("printer is not connected, try to connect", "printerID", q.printer.ID)
-				if err := q.printer.Connect(); err != nil {
-					slog.Error("cannot connect to printer", "printerID", q.printer.ID, "addr", q.printer.Addr, "error", err)
-					continue
-				}
+		if ctx.Err() != nil {
+			return
+		}
+		q.applyConfig(ctx)
+		if !q.waitReconnect(ctx) {
+			continue
+		}
+		// connection of per job printer is not kept to poll it while idle
+		if !q.printer.IsPerJob() && !q.awaitReady(ctx) {
+			continue
+		}
+		if q.isHeld() {
+			select {
+			case <-q.notify:
+			case <-q.nextPoll():
+			case <-ctx.Done():
+				return
 			}
-			for i := 0; i < task.Quantity; i++ {
-				err := q.printer.Enqueue(task.Document)
-				if err != nil {
-					slog.Error("queue: printing failed", "printerID", q.printer.ID, "error", err)
-				}
-				time.Sleep(task.Timeout)
+			continue
+		}
+		task, ok := q.next()
+		if !ok {
+			select {
+			case <-q.notify:
+				continue
+			case <-q.nextPoll():
+				continue
+			case <-ctx.Done():
+				return
 			}
+		}
+		slog.Debug("queue: got task to process", "jobID", task.Job.ID)
+		if task.Job.IsExpired(time.Now()) {
+			slog.Debug("queue: skip expired job", "jobID", task.Job.ID)
+			q.finish(ctx, &task, errExpired)
+			continue
+		}
+		q.process(ctx, &task)
+		if q.printer.IsPerJob() {
+			q.disconnect()
+		}
+	}
+}
+
+// connect opens connection to the printer, failure postpones the next
+// attempt with backoff.
+func (q *Queue) connect(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, q.conn.DialTimeout)
+	defer cancel()
+	q.mu.Lock()
+	q.health.lastConnectAt = time.Now()
+	q.mu.Unlock()
+	err := q.printer.Connect(ctx)
+	q.setConnected()
+	q.setError(err)
+	q.backoff(err)
+	if err != nil {
+		slog.Error("cannot connect to printer", "printerID", q.printerID, "addr", q.printer.Addr, "error", err)
+	}
+	return err
+}
+
+func (q *Queue) disconnect() {
+	if err := q.printer.Close(); err != nil {
+		slog.Error("closing printer connection", "printerID", q.printerID, "error", err)
+	}
+	q.setConnected()
+}
+
+// send writes the document to the printer within the write timeout.
+func (q *Queue) send(ctx context.Context, document printer.Printable) error {
+	if !q.printer.IsConnected() {
+		slog.Debug("printer is not connected, try to connect", "printerID", q.printerID)
+		if err := q.connect(ctx); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, q.conn.WriteTimeout)
+	defer cancel()
+	err := q.printer.Enqueue(ctx, document)
+	q.setConnected()
+	q.setError(err)
+	q.backoff(err)
+	if err == nil {
+		q.mu.Lock()
+		q.health.lastPrintAt = time.Now()
+		q.mu.Unlock()
+	}
+	return err
+}
+
+// setError keeps the error of the last attempt to connect or print.
+func (q *Queue) setError(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.health.lastError = ""
+	if err != nil {
+		q.health.lastError = err.Error()
+	}
+}
+
+// awaitReady polls the printer when it is due and waits while the printer
+// reports an error. It returns false if waiting is interrupted by new task,
+// update of the printer or ctx.
+func (q *Queue) awaitReady(ctx context.Context) bool {
+	if q.pollDue() {
+		q.poll(ctx)
+	}
+	if q.isReady() {
+		return true
+	}
+	select {
+	case <-q.nextPoll():
+	case <-q.notify:
+	case <-ctx.Done():
+	}
+	return false
+}
+
+// pace waits until the printer has room in its buffer for the next
+// document. The timeout is used instead if the printer cannot report
+// its buffer.
+func (q *Queue) pace(ctx context.Context, timeout time.Duration) {
+	for q.flowEnabled() {
+		status, ok := q.poll(ctx)
+		if !ok {
+			break
+		}
+		// not ready printer is awaited before the next copy anyway
+		if !status.Ready() || q.hasCapacity(status) {
+			return
+		}
+		slog.Debug("queue: printer buffer is full, wait", "printerID", q.printerID,
+			"formats", status.FormatsInBuffer, "labels", status.LabelsRemaining)
+		select {
+		case <-time.After(q.conn.FlowInterval):
 		case <-ctx.Done():
-			slog.Debug("processing queue for printer is done", "printerID", q.printer.ID)
+			return
+		}
+	}
+	select {
+	case <-time.After(timeout):
+	case <-ctx.Done():
+	}
+}
+
+// hasCapacity reports whether the printer can take one more format, the
+// batch that is printing at the moment takes a place as well.
+func (q *Queue) hasCapacity(status printer.Status) bool {
+	buffered := status.FormatsInBuffer
+	if status.LabelsRemaining > 0 {
+		buffered++
+	}
+	return !status.BufferFull && buffered < q.conn.MaxBuffered
+}
+
+func (q *Queue) pollEnabled() bool {
+	return q.conn.StatusInterval > 0 && !q.statusUnsupported && q.printer.Type == printer.TypeZPL
+}
+
+func (q *Queue) flowEnabled() bool {
+	return q.conn.FlowInterval > 0 && q.pollEnabled()
+}
+
+func (q *Queue) pollDue() bool {
+	return q.pollEnabled() && time.Since(q.polledAt) >= q.conn.StatusInterval
+}
+
+// nextPoll fires when the next poll is due, it never fires if polling is
+// disabled or the printer is connected per job, since idle per job printer
+// is not polled and the poll would never stop being due.
+func (q *Queue) nextPoll() <-chan time.Time {
+	if !q.pollEnabled() || q.printer.IsPerJob() {
+		return nil
+	}
+	return time.After(time.Until(q.polledAt.Add(q.conn.StatusInterval)))
+}
+
+// poll queries the status of the printer, failed query does not block
+// printing, since printing itself reports connection errors.
+func (q *Queue) poll(ctx context.Context) (printer.Status, bool) {
+	q.polledAt = time.Now()
+	if !q.printer.IsConnected() {
+		if err := q.connect(ctx); err != nil {
+			q.setStatus(nil, err)
+			return printer.Status{}, false
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+	status, err := q.printer.QueryStatus(ctx)
+	q.setConnected()
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		q.statusTimeouts++
+	} else {
+		q.statusTimeouts = 0
+	}
+	if errors.Is(err, printer.ErrStatusUnsupported) || q.statusTimeouts >= maxStatusTimeouts {
+		slog.Info("queue: printer cannot report status, polling is disabled", "printerID", q.printerID)
+		q.statusUnsupported = true
+	}
+	if err != nil {
+		slog.Warn("queue: cannot query printer status", "printerID", q.printerID, "error", err)
+		q.setStatus(nil, err)
+		return printer.Status{}, false
+	}
+	if !status.Ready() {
+		slog.Warn("queue: printer is not ready, dispatch is paused", "printerID", q.printerID, "status", status)
+	}
+	q.setStatus(&status, nil)
+	return status, true
+}
+
+func (q *Queue) setStatus(status *printer.Status, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	q.status.CheckedAt = &now
+	q.status.Status = status
+	q.status.Error = ""
+	if err != nil {
+		q.status.Error = err.Error()
+	}
+	// printing is not paused if status is unknown
+	q.status.Ready = status == nil || status.Ready()
+}
+
+func (q *Queue) isReady() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.status.Ready
+}
+
+// backoff counts failures in a row and sets the time of the next reconnect.
+func (q *Queue) backoff(err error) {
+	if err == nil {
+		q.failures = 0
+		return
+	}
+	q.failures++
+	delay := q.conn.delay(q.failures)
+	q.reconnectAt = time.Now().Add(delay)
+	slog.Debug("queue: reconnect is postponed", "printerID", q.printerID, "failures", q.failures, "delay", delay)
+}
+
+// waitReconnect waits for the time of reconnect after failures, it returns
+// false if waiting is interrupted by new task, update of the printer or ctx.
+func (q *Queue) waitReconnect(ctx context.Context) bool {
+	if q.failures == 0 {
+		return true
+	}
+	delay := time.Until(q.reconnectAt)
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-q.notify:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// next pops the task with the highest priority and makes it current.
+func (q *Queue) next() (Task, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.tasks) == 0 {
+		return Task{}, false
+	}
+	t := heap.Pop(&q.tasks).(queuedTask)
+	current := t.Job
+	q.current = &current
+	q.canceled = false
+	return t.Task, true
+}
+
+// process prints copies of the task that are not printed yet, so a job
+// restored after restart or retried continues from the last printed copy.
+// ZPL copies are made by the printer in batches, pause and cancel stop the
+// job between them.
+// Copies sent to verified printer are confirmed by its label counter.
+func (q *Queue) process(ctx context.Context, task *Task) {
+	task.Job.Status = job.StatusPrinting
+	q.progress(ctx, &task.Job)
+	var (
+		sent      int
+		before    int64
+		verifyErr error
+	)
+	if q.printer.Verify {
+		before, verifyErr = q.labelCount(ctx)
+	}
+	for task.Job.Printed < task.Job.Quantity {
+		if q.isCanceled() {
+			break
+		}
+		if ctx.Err() != nil {
+			// leave job unfinished to restore it on the next start
+			return
+		}
+		if q.isPaused() {
+			q.hold(ctx, task)
+			return
+		}
+		if !q.awaitReady(ctx) {
+			continue
+		}
+		document, copies := q.copies(task)
+		err := q.send(ctx, document)
+		if err != nil {
+			slog.Error("queue: printing failed", "printerID", q.printerID, "jobID", task.Job.ID, "error", err)
+			q.finish(ctx, task, err)
+			return
+		}
+		sent += copies
+		task.Job.Printed += copies
+		q.progress(ctx, &task.Job)
+		q.pace(ctx, task.Job.Timeout)
+	}
+	// cancel that comes after the last copy does not stop anything
+	if q.printer.Verify && sent > 0 && task.Job.Printed == task.Job.Quantity {
+		if verifyErr == nil {
+			verifyErr = q.confirm(ctx, before, sent)
+		}
+		if verifyErr != nil {
+			slog.Warn("queue: printed labels are not confirmed", "printerID", q.printerID, "jobID", task.Job.ID, "error", verifyErr)
+			q.finish(ctx, task, fmt.Errorf("%w: %w", errUnconfirmed, verifyErr))
+			return
+		}
+	}
+	q.finish(ctx, task, nil)
+}
+
+// copies returns the document to send and the number of copies it makes.
+// Printer that can make copies itself gets the next batch of copies that
+// are left, serial numbers of the batch continue from the printed copies.
+// Others get the document per copy.
+func (q *Queue) copies(task *Task) (printer.Printable, int) {
+	if task.Job.Quantity < 2 {
+		return task.Document, 1
+	}
+	doc, err := task.Document.Print(q.printer.Type)
+	if err != nil {
+		// the error is reported by send
+		return task.Document, 1
+	}
+	batch := min(task.Job.Quantity-task.Job.Printed, copiesBatch)
+	if doc, ok := printer.Copies(doc, q.printer.Type, task.Job.Printed, batch); ok {
+		slog.Debug("queue: copies are made by printer", "printerID", q.printerID, "jobID", task.Job.ID, "copies", batch)
+		return printer.Raw(doc), batch
+	}
+	return task.Document, 1
+}
+
+// labelCount reads the label counter of the printer.
+func (q *Queue) labelCount(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+	count, err := q.printer.QueryLabelCount(ctx)
+	q.setConnected()
+	return count, err
+}
+
+// confirm waits until the label counter of the printer grows by the number
+// of sent copies, printer that is still printing is waited up to the
+// verify timeout.
+func (q *Queue) confirm(ctx context.Context, before int64, sent int) error {
+	deadline := time.Now().Add(q.conn.VerifyTimeout)
+	for {
+		after, err := q.labelCount(ctx)
+		if err != nil {
+			return err
+		}
+		printed := after - before
+		if printed >= int64(sent) {
 			return nil
 		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d of %d labels printed", max(printed, 0), sent)
+		}
+		select {
+		case <-time.After(verifyInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// finish sets the final status of the job, failed job is sent to retry
+// while it has attempts left and goes to dead letters after that.
+func (q *Queue) finish(ctx context.Context, task *Task, err error) {
+	j := &task.Job
+	q.mu.Lock()
+	// the job is not canceled if all copies are sent before the cancel
+	canceled := q.canceled && j.Printed < j.Quantity
+	q.current = nil
+	q.canceled = false
+	retry := !canceled && isRetryable(err) && j.Attempts+1 < j.Retry.MaxAttempts
+	if retry {
+		// the job is not current anymore, but it still can be canceled
+		q.retrying = j.ID
+	}
+	q.mu.Unlock()
+
+	switch {
+	case canceled:
+		j.Status = job.StatusCanceled
+	case err == nil:
+		j.Status = job.StatusDone
+		q.mu.Lock()
+		q.health.jobsPrinted++
+		q.mu.Unlock()
+	case errors.Is(err, errExpired):
+		j.Status = job.StatusExpired
+	case errors.Is(err, errUnconfirmed):
+		// labels may have come out, so the job is not retried to avoid duplicates
+		j.Status = job.StatusUnconfirmed
+		j.LastError = err.Error()
+	default:
+		j.LastError = err.Error()
+		j.Attempts++
+		if retry {
+			q.retryLater(ctx, task)
+			return
+		}
+		slog.Warn("queue: job exhausted all attempts", "jobID", j.ID, "attempts", j.Attempts)
+		j.Status = job.StatusDeadLetter
+	}
+	q.update(ctx, j)
+}
+
+func (q *Queue) retryLater(ctx context.Context, task *Task) {
+	notBefore := time.Now().Add(task.Job.Retry.Delay(task.Job.Attempts))
+	task.Job.NotBefore = &notBefore
+	task.Job.Status = job.StatusScheduled
+	q.update(ctx, &task.Job)
+	slog.Info("queue: job is scheduled to retry", "jobID", task.Job.ID, "attempt", task.Job.Attempts+1, "notBefore", notBefore)
+	select {
+	case q.retry <- *task:
+	case <-ctx.Done():
+		// job is stored as scheduled and will be restored on the next start
+	}
+	q.mu.Lock()
+	q.retrying = 0
+	q.mu.Unlock()
+}
+
+// isRetryable reports whether the job failed with the error may be tried
+// again, expired jobs and unconfirmed labels are final.
+func isRetryable(err error) bool {
+	return err != nil && !errors.Is(err, errExpired) && !errors.Is(err, errUnconfirmed)
+}
+
+// applyConfig reconnects to the printer if its configuration is updated.
+func (q *Queue) applyConfig(ctx context.Context) {
+	q.mu.Lock()
+	config := q.config
+	q.config = nil
+	q.mu.Unlock()
+	if config == nil {
+		return
+	}
+	if sameConnection(q.printer, *config) {
+		slog.Info("queue: printer is updated", "printerID", q.printerID)
+		q.printer.Type = config.Type
+		q.printer.Comment = config.Comment
+		q.printer.Verify = config.Verify
+		q.printer.Capabilities = config.Capabilities
+		return
+	}
+
+	slog.Info("queue: printer is updated, reconnect", "printerID", q.printerID, "addr", config.Addr)
+	q.disconnect()
+	q.printer = *config
+	// failures and status of the previous address do not affect the new one
+	q.failures = 0
+	q.polledAt = time.Time{}
+	q.statusUnsupported = false
+	q.statusTimeouts = 0
+	q.mu.Lock()
+	q.status = PrinterStatus{PrinterID: q.printerID, Ready: true}
+	q.health.lastError = ""
+	q.mu.Unlock()
+	if !q.printer.IsPerJob() {
+		q.connect(ctx)
+	}
+}
+
+// sameConnection reports whether the printers are connected the same way,
+// so the update does not need reconnect.
+func sameConnection(a, b printer.Printer) bool {
+	if a.Addr != b.Addr || a.Mode != b.Mode || (a.TLS == nil) != (b.TLS == nil) {
+		return false
+	}
+	return a.TLS == nil || *a.TLS == *b.TLS
+}
+
+// isHeld reports whether the queue must not take the next task, draining
+// queue is paused here, since its current job is finished.
+func (q *Queue) isHeld() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.dispatch == DispatchDraining {
+		slog.Info("queue: drained, dispatch is paused", "printerID", q.printerID)
+		q.dispatch = DispatchPaused
+	}
+	return q.dispatch == DispatchPaused
+}
+
+func (q *Queue) isPaused() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dispatch == DispatchPaused
+}
+
+// hold puts the current task back to the queue ahead of tasks of the same
+// priority, so it continues first when the queue is resumed. The task takes
+// the slot it kept while printing, so the queue does not overflow.
+func (q *Queue) hold(ctx context.Context, task *Task) {
+	slog.Info("queue: paused, job is held", "printerID", q.printerID, "jobID", task.Job.ID, "printed", task.Job.Printed)
+	task.Job.Status = job.StatusQueued
+	q.update(ctx, &task.Job)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	heap.Push(&q.tasks, queuedTask{Task: *task, seq: 0})
+	q.current = nil
+	q.canceled = false
+}
+
+func (q *Queue) isCanceled() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.canceled
+}
+
+func (q *Queue) setConnected() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.connected = q.printer.IsConnected()
+}
+
+// progress stores the job and makes its copy visible in the queue state.
+func (q *Queue) progress(ctx context.Context, j *job.Job) {
+	q.update(ctx, j)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.current != nil && q.current.ID == j.ID {
+		*q.current = *j
+	}
+}
+
+func (q *Queue) update(ctx context.Context, j *job.Job) {
+	if err := q.jobs.Update(context.WithoutCancel(ctx), j); err != nil {
+		slog.Error("queue: cannot update job", "jobID", j.ID, "error", err)
 	}
 }
@@ -0,0 +1,935 @@
+package printingqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zhurd/internal/job"
+	"zhurd/internal/printer"
+)
+
+func TestQueueOrder(t *testing.T) {
+	q := New(printer.New("ZPL", "127.0.0.1:9100", ""), 8, ConnPolicy{}, nil, nil)
+	enqueued := []job.Job{
+		{ID: 1, Priority: 0},
+		{ID: 2, Priority: 0},
+		{ID: 3, Priority: 10},
+		{ID: 4, Priority: 5},
+		{ID: 5, Priority: 10},
+	}
+	for _, j := range enqueued {
+		if err := q.Enqueue(Task{Job: j}); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+
+	expected := []int64{3, 5, 4, 1, 2}
+	for _, id := range expected {
+		task, ok := q.next()
+		if !ok {
+			t.Fatalf("expected task %d, but queue is empty\n", id)
+		}
+		if task.Job.ID != id {
+			t.Errorf("expected: %d, got: %d\n", id, task.Job.ID)
+		}
+	}
+	if _, ok := q.next(); ok {
+		t.Errorf("expected empty queue\n")
+	}
+}
+
+func TestQueueMoveToFront(t *testing.T) {
+	q := New(printer.New("ZPL", "127.0.0.1:9100", ""), 8, ConnPolicy{}, nil, nil)
+	for _, j := range []job.Job{
+		{ID: 1, Priority: 0},
+		{ID: 2, Priority: 10},
+		{ID: 3, Priority: 0},
+		{ID: 4, Priority: 5},
+	} {
+		if err := q.Enqueue(Task{Job: j}); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+	// low priority jobs are moved after the high priority one
+	if !q.MoveToFront(2) || !q.MoveToFront(3) || !q.MoveToFront(1) {
+		t.Fatalf("expected queued jobs to be moved\n")
+	}
+	if q.MoveToFront(42) {
+		t.Errorf("expected unknown job not to be moved\n")
+	}
+
+	// the last moved job goes first regardless of priority
+	expected := []int64{1, 3, 2, 4}
+	pending := q.Pending()
+	if len(pending) != len(expected) {
+		t.Fatalf("expected: %d, got: %d\n", len(expected), len(pending))
+	}
+	for i, id := range expected {
+		if pending[i].ID != id {
+			t.Errorf("expected: %d, got: %d\n", id, pending[i].ID)
+		}
+		if task, ok := q.next(); !ok || task.Job.ID != id {
+			t.Errorf("expected: %d, got: %v\n", id, task.Job.ID)
+		}
+	}
+}
+
+func TestQueueCopies(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	cp := &counterPrinter{}
+	go cp.serve(l)
+
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	p := printer.New("ZPL", l.Addr().String(), "")
+	p.Verify = true
+	q := New(p, 8, ConnPolicy{StatusInterval: -1, VerifyTimeout: time.Second}, jobs, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	q.start(ctx, &wg)
+
+	// copies left after restart are made by the printer with ^PQ in batches
+	j := job.New(0, 25, 0)
+	j.Printed = 2
+	if err := jobs.Store(ctx, &j); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if err := q.Enqueue(Task{Job: j, Document: printer.Raw("^XA^SN001,1,Y^FS^XZ")}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		done, err := jobs.Get(ctx, j.ID)
+		return err == nil && done.IsFinished()
+	})
+	done, err := jobs.Get(ctx, j.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if done.Status != job.StatusDone || done.Printed != 25 {
+		t.Errorf("expected done job with %d printed, got: %v with %d\n", 25, done.Status, done.Printed)
+	}
+	if n := cp.count.Load(); n != 23 {
+		t.Errorf("expected: %d, got: %d\n", 23, n)
+	}
+}
+
+func TestQueueAccepts(t *testing.T) {
+	ucs := []struct {
+		desc     string
+		from     printer.Printer
+		to       printer.Printer
+		expected bool
+	}{
+		{
+			desc:     "same type without capabilities",
+			from:     printer.Printer{Type: "ZPL"},
+			to:       printer.Printer{Type: "ZPL", Capabilities: &printer.Capabilities{DPI: 300}},
+			expected: true,
+		},
+		{
+			desc: "other type",
+			from: printer.Printer{Type: "ZPL"},
+			to:   printer.Printer{Type: "EPL"},
+		},
+		{
+			desc: "unknown capabilities",
+			from: printer.Printer{Type: "ZPL", Capabilities: &printer.Capabilities{DPI: 203}},
+			to:   printer.Printer{Type: "ZPL"},
+		},
+		{
+			desc: "other resolution",
+			from: printer.Printer{Type: "ZPL", Capabilities: &printer.Capabilities{DPI: 300, PrintWidth: 832}},
+			to:   printer.Printer{Type: "ZPL", Capabilities: &printer.Capabilities{DPI: 203, PrintWidth: 832}},
+		},
+		{
+			desc: "other width",
+			from: printer.Printer{Type: "ZPL", Capabilities: &printer.Capabilities{DPI: 203, PrintWidth: 832}},
+			to:   printer.Printer{Type: "ZPL", Capabilities: &printer.Capabilities{DPI: 203, PrintWidth: 448}},
+		},
+		{
+			desc: "missing option",
+			from: printer.Printer{Type: "ZPL", Capabilities: &printer.Capabilities{DPI: 203, Options: []string{printer.OptionCutter}}},
+			to:   printer.Printer{Type: "ZPL", Capabilities: &printer.Capabilities{DPI: 203}},
+		},
+		{
+			desc:     "more options",
+			from:     printer.Printer{Type: "ZPL", Capabilities: &printer.Capabilities{DPI: 203, Options: []string{printer.OptionCutter}}},
+			to:       printer.Printer{Type: "ZPL", Capabilities: &printer.Capabilities{DPI: 203, Options: []string{printer.OptionRFID, printer.OptionCutter}}},
+			expected: true,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			from := New(us.from, 1, ConnPolicy{}, nil, nil)
+			to := New(us.to, 1, ConnPolicy{}, nil, nil)
+			if accepts := to.Accepts(from); accepts != us.expected {
+				t.Errorf("expected: %v, got: %v\n", us.expected, accepts)
+			}
+		})
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	q := New(printer.New("ZPL", "127.0.0.1:9100", ""), 1, ConnPolicy{}, nil, nil)
+	if err := q.Enqueue(Task{Job: job.Job{ID: 1}}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if !q.IsFull() {
+		t.Errorf("expected full queue\n")
+	}
+	if err := q.Enqueue(Task{Job: job.Job{ID: 2}}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected: %v, got: %v\n", ErrQueueFull, err)
+	}
+}
+
+func TestQueueCancelPending(t *testing.T) {
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	q := New(printer.New("ZPL", "127.0.0.1:9100", ""), 8, ConnPolicy{}, jobs, nil)
+	var enqueued []job.Job
+	for range 2 {
+		j := job.New(0, 1, 0)
+		if err := jobs.Store(context.Background(), &j); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		if err := q.Enqueue(Task{Job: j}); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		enqueued = append(enqueued, j)
+	}
+
+	if err := q.Cancel(context.Background(), enqueued[0]); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if pending := q.Pending(); len(pending) != 1 || pending[0].ID != enqueued[1].ID {
+		t.Errorf("expected only job %d pending, got: %v\n", enqueued[1].ID, pending)
+	}
+	stored, err := jobs.Get(context.Background(), enqueued[0].ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.Status != job.StatusCanceled {
+		t.Errorf("expected: %v, got: %v\n", job.StatusCanceled, stored.Status)
+	}
+	if err := q.Cancel(context.Background(), enqueued[0]); !errors.Is(err, ErrJobNotQueued) {
+		t.Errorf("expected: %v, got: %v\n", ErrJobNotQueued, err)
+	}
+}
+
+func TestQueueCancelPrinting(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	sp := &statusPrinter{}
+	go sp.serve(l)
+
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	q := New(printer.New("ZPL", l.Addr().String(), ""), 8, ConnPolicy{StatusInterval: -1}, jobs, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	q.start(ctx, &wg)
+
+	// copies are made by the printer in batches, the job stops between them
+	j := job.New(0, 25, 100*time.Millisecond)
+	if err := jobs.Store(ctx, &j); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if err := q.Enqueue(Task{Job: j, Document: printer.Raw("^XA^XZ")}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	waitFor(t, 5*time.Second, func() bool { return sp.printed.Load() >= 1 })
+	if err := q.Cancel(ctx, j); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		stored, err := jobs.Get(ctx, j.ID)
+		return err == nil && stored.IsFinished()
+	})
+	stored, err := jobs.Get(ctx, j.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.Status != job.StatusCanceled || stored.Printed >= 25 || int32(stored.Printed) != sp.printed.Load() {
+		t.Errorf("expected canceled job with %d of 25 printed, got: %v with %d\n", sp.printed.Load(), stored.Status, stored.Printed)
+	}
+
+	// job that has sent all its copies is done, even if it is canceled
+	printed := sp.printed.Load()
+	j = job.New(0, 3, 300*time.Millisecond)
+	if err := jobs.Store(ctx, &j); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if err := q.Enqueue(Task{Job: j, Document: printer.Raw("^XA^XZ")}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	waitFor(t, 5*time.Second, func() bool { return sp.printed.Load() == printed+3 })
+	if err := q.Cancel(ctx, j); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		stored, err := jobs.Get(ctx, j.ID)
+		return err == nil && stored.IsFinished()
+	})
+	stored, err = jobs.Get(ctx, j.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if stored.Status != job.StatusDone || stored.Printed != 3 {
+		t.Errorf("expected done job with 3 printed, got: %v with %d\n", stored.Status, stored.Printed)
+	}
+}
+
+func TestQueueHoldKeepsSlot(t *testing.T) {
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	q := New(printer.New("ZPL", "127.0.0.1:9100", ""), 1, ConnPolicy{}, jobs, nil)
+	j := job.New(0, 1, 0)
+	if err := jobs.Store(context.Background(), &j); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if err := q.Enqueue(Task{Job: j}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	task, ok := q.next()
+	if !ok {
+		t.Fatalf("expected task %d, but queue is empty\n", j.ID)
+	}
+	// the printing task keeps its slot
+	if err := q.Enqueue(Task{Job: job.Job{ID: 42}}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected: %v, got: %v\n", ErrQueueFull, err)
+	}
+	q.hold(context.Background(), &task)
+	if n := q.Load(); n != 1 {
+		t.Errorf("expected: %d, got: %d\n", 1, n)
+	}
+	if !q.IsFull() {
+		t.Errorf("expected full queue\n")
+	}
+}
+
+func TestQueueReconfigure(t *testing.T) {
+	q := New(printer.New("ZPL", "127.0.0.1:9100", ""), 8, ConnPolicy{}, nil, nil)
+	for _, id := range []int64{1, 2} {
+		if err := q.Enqueue(Task{Job: job.Job{ID: id}}); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+
+	if err := q.Reconfigure(printer.New("ZPL", "127.0.0.1:9101", "")); err != nil {
+		t.Errorf("got error: %s\n", err)
+	}
+	if st := q.State(); st.Depth != 2 {
+		t.Errorf("expected: %d, got: %d\n", 2, st.Depth)
+	}
+
+	// pending jobs are rendered for ZPL, so they are kept and the type is not changed
+	if err := q.Reconfigure(printer.New("EPL", "127.0.0.1:9101", "")); !errors.Is(err, ErrJobsPending) {
+		t.Errorf("expected: %v, got: %v\n", ErrJobsPending, err)
+	}
+	if st := q.State(); st.Depth != 2 {
+		t.Errorf("expected: %d, got: %d\n", 2, st.Depth)
+	}
+	if q.PrinterType() != "ZPL" {
+		t.Errorf("expected: %s, got: %s\n", "ZPL", q.PrinterType())
+	}
+
+	q.Take(func(Task) bool { return true })
+	if err := q.Reconfigure(printer.New("EPL", "127.0.0.1:9101", "")); err != nil {
+		t.Errorf("got error: %s\n", err)
+	}
+	if q.PrinterType() != "EPL" {
+		t.Errorf("expected: %s, got: %s\n", "EPL", q.PrinterType())
+	}
+}
+
+func TestConnPolicyDelay(t *testing.T) {
+	conn := ConnPolicy{Backoff: time.Second, MaxBackoff: 4 * time.Second}
+	expected := map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 4 * time.Second,
+	}
+	for failures, max := range expected {
+		delay := conn.delay(failures)
+		if delay < max/2 || delay > max {
+			t.Errorf("expected delay in [%v, %v], got: %v\n", max/2, max, delay)
+		}
+	}
+}
+
+func TestQueueConnectionMode(t *testing.T) {
+	ucs := []struct {
+		desc     string
+		mode     string
+		expected int
+	}{
+		{
+			desc:     "persistent",
+			mode:     printer.ModePersistent,
+			expected: 1,
+		},
+		{
+			desc:     "per job",
+			mode:     printer.ModePerJob,
+			expected: 2,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			defer l.Close()
+			var accepted atomic.Int32
+			go func() {
+				for {
+					c, err := l.Accept()
+					if err != nil {
+						return
+					}
+					accepted.Add(1)
+					go io.Copy(io.Discard, c)
+				}
+			}()
+
+			jobs, err := job.NewMemory()
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			p := printer.New("ZPL", l.Addr().String(), "")
+			p.Mode = us.mode
+			q := New(p, 8, ConnPolicy{StatusInterval: -1}, jobs, nil)
+			for i := 0; i < 2; i++ {
+				j := job.New(p.ID, 1, 0)
+				j.Retry.MaxAttempts = 1
+				if err := jobs.Store(context.Background(), &j); err != nil {
+					t.Fatalf("got error: %s\n", err)
+				}
+				if err := q.Enqueue(Task{Job: j, Document: printer.Raw("^XA^XZ")}); err != nil {
+					t.Fatalf("got error: %s\n", err)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			q.start(ctx, &wg)
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) {
+				done, err := jobs.List(context.Background(), job.Filter{Status: job.StatusDone, Limit: 2})
+				if err != nil {
+					t.Fatalf("got error: %s\n", err)
+				}
+				if len(done) == 2 {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			cancel()
+			wg.Wait()
+
+			if n := int(accepted.Load()); n != us.expected {
+				t.Errorf("expected: %d, got: %d\n", us.expected, n)
+			}
+		})
+	}
+}
+
+func TestQueuePerJobIdle(t *testing.T) {
+	p := printer.New("ZPL", "127.0.0.1:9100", "")
+	p.Mode = printer.ModePerJob
+	q := New(p, 8, ConnPolicy{StatusInterval: time.Second}, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	q.start(ctx, &wg)
+
+	// spinning loop allocates a timer on every pass
+	allocs := func() uint64 {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return m.Mallocs
+	}
+	for _, desc := range []string{"idle", "paused"} {
+		before := allocs()
+		time.Sleep(300 * time.Millisecond)
+		if n := allocs() - before; n > 10000 {
+			t.Errorf("%s: expected idle queue to wait, got %d allocations\n", desc, n)
+		}
+		q.Pause()
+	}
+}
+
+// statusPrinter answers ~HS with paper out until it is loaded and counts
+// printed labels.
+type statusPrinter struct {
+	loaded  atomic.Bool
+	printed atomic.Int32
+}
+
+func (p *statusPrinter) serve(l net.Listener) {
+	const (
+		ready    = "\x02030,0,0,0245,000,0,0,0,000,0,0,0\x03\r\n\x02001,0,0,0,1,2,4,0,00000000,1,000\x03\r\n\x021234,0\x03\r\n"
+		paperOut = "\x02030,1,0,0245,000,0,0,0,000,0,0,0\x03\r\n\x02001,0,0,0,1,2,4,0,00000000,1,000\x03\r\n\x021234,0\x03\r\n"
+		noErrors = "\x02\r\nPRINTER STATUS\r\n   ERRORS:         0 00000000 00000000\r\n   WARNINGS:       0 00000000 00000000\r\n\x03\r\n"
+	)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			buf := make([]byte, 256)
+			for {
+				n, err := conn.Read(buf)
+				if err != nil {
+					return
+				}
+				data := string(buf[:n])
+				p.printed.Add(int32(labels(data)))
+				if strings.Contains(data, "~HQES") {
+					conn.Write([]byte(noErrors))
+				} else if strings.Contains(data, "~HS") {
+					if p.loaded.Load() {
+						conn.Write([]byte(ready))
+					} else {
+						conn.Write([]byte(paperOut))
+					}
+				}
+			}
+		}()
+	}
+}
+
+func TestQueuePausedByStatus(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	sp := &statusPrinter{}
+	go sp.serve(l)
+
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	q := New(printer.New("ZPL", l.Addr().String(), ""), 8, ConnPolicy{StatusInterval: 50 * time.Millisecond}, jobs, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	q.start(ctx, &wg)
+
+	j := job.New(0, 1, 0)
+	if err := jobs.Store(ctx, &j); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if err := q.Enqueue(Task{Job: j, Document: printer.Raw("^XA^XZ")}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if n := sp.printed.Load(); n != 0 {
+		t.Errorf("expected no labels printed while paper is out, got: %d\n", n)
+	}
+	if q.State().Ready || q.PrinterStatus().Status == nil || !q.PrinterStatus().Status.PaperOut {
+		t.Errorf("expected paper out, got: %+v\n", q.PrinterStatus())
+	}
+
+	sp.loaded.Store(true)
+	deadline := time.Now().Add(5 * time.Second)
+	for sp.printed.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := sp.printed.Load(); n != 1 {
+		t.Errorf("expected: %d, got: %d\n", 1, n)
+	}
+}
+
+// bufferPrinter reports the received format in its buffer for a few status
+// queries and counts formats received while the buffer is not empty.
+type bufferPrinter struct {
+	printed  atomic.Int32
+	overruns atomic.Int32
+}
+
+func (p *bufferPrinter) serve(l net.Listener) {
+	const noErrors = "\x02\r\nPRINTER STATUS\r\n   ERRORS:         0 00000000 00000000\r\n   WARNINGS:       0 00000000 00000000\r\n\x03\r\n"
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			buf := make([]byte, 256)
+			busy := 0
+			for {
+				n, err := conn.Read(buf)
+				if err != nil {
+					return
+				}
+				data := string(buf[:n])
+				if formats := strings.Count(data, "^XA"); formats > 0 {
+					if busy > 0 {
+						p.overruns.Add(1)
+					}
+					p.printed.Add(int32(formats))
+					busy = 3
+				}
+				if strings.Contains(data, "~HQES") {
+					conn.Write([]byte(noErrors))
+				} else if strings.Contains(data, "~HS") {
+					formats := 0
+					if busy > 0 {
+						formats = 1
+						busy--
+					}
+					fmt.Fprintf(conn, "\x02030,0,0,0245,%03d,0,0,0,000,0,0,0\x03\r\n\x02001,0,0,0,1,2,4,0,00000000,1,000\x03\r\n\x021234,0\x03\r\n", formats)
+				}
+			}
+		}()
+	}
+}
+
+func TestQueueFlowControl(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	bp := &bufferPrinter{}
+	go bp.serve(l)
+
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	conn := ConnPolicy{StatusInterval: time.Hour, FlowInterval: 10 * time.Millisecond, MaxBuffered: 1}
+	q := New(printer.New("ZPL", l.Addr().String(), ""), 8, conn, jobs, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	q.start(ctx, &wg)
+
+	// timeout of the jobs is not waited, since the printer reports its buffer
+	for range 3 {
+		j := job.New(0, 1, time.Hour)
+		if err := jobs.Store(ctx, &j); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		if err := q.Enqueue(Task{Job: j, Document: printer.Raw("^XA^XZ")}); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for bp.printed.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := bp.printed.Load(); n != 3 {
+		t.Errorf("expected: %d, got: %d\n", 3, n)
+	}
+	if n := bp.overruns.Load(); n != 0 {
+		t.Errorf("expected no formats sent to the full buffer, got: %d\n", n)
+	}
+}
+
+// counterPrinter answers SGD getvar with the label counter, which grows by
+// every received label unless the printer is jammed.
+type counterPrinter struct {
+	jammed bool
+	count  atomic.Int64
+}
+
+func (p *counterPrinter) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			buf := make([]byte, 256)
+			for {
+				n, err := conn.Read(buf)
+				if err != nil {
+					return
+				}
+				data := string(buf[:n])
+				if !p.jammed {
+					p.count.Add(labels(data))
+				}
+				if strings.Contains(data, "getvar") {
+					fmt.Fprintf(conn, "\"%d\"", p.count.Load())
+				}
+			}
+		}()
+	}
+}
+
+// labels counts labels of the received formats, a format makes as many
+// labels as its ^PQ tells.
+func labels(data string) int64 {
+	var n int64
+	for _, format := range strings.Split(data, "^XA")[1:] {
+		copies := int64(1)
+		if i := strings.Index(format, "^PQ"); i >= 0 {
+			digits := format[i+3:]
+			if j := strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }); j >= 0 {
+				digits = digits[:j]
+			}
+			if c, err := strconv.ParseInt(digits, 10, 64); err == nil {
+				copies = c
+			}
+		}
+		n += copies
+	}
+	return n
+}
+
+func TestQueueVerify(t *testing.T) {
+	ucs := []struct {
+		desc     string
+		jammed   bool
+		expected job.Status
+	}{
+		{
+			desc:     "confirmed",
+			expected: job.StatusDone,
+		},
+		{
+			desc:     "jammed",
+			jammed:   true,
+			expected: job.StatusUnconfirmed,
+		},
+	}
+
+	for _, us := range ucs {
+		us := us
+		t.Run(us.desc, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			defer l.Close()
+			cp := &counterPrinter{jammed: us.jammed}
+			cp.count.Store(100)
+			go cp.serve(l)
+
+			jobs, err := job.NewMemory()
+			if err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			p := printer.New("ZPL", l.Addr().String(), "")
+			p.Verify = true
+			conn := ConnPolicy{StatusInterval: -1, VerifyTimeout: 100 * time.Millisecond}
+			q := New(p, 8, conn, jobs, nil)
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			defer wg.Wait()
+			defer cancel()
+			q.start(ctx, &wg)
+
+			j := job.New(0, 2, 0)
+			j.Retry.MaxAttempts = 3
+			if err := jobs.Store(ctx, &j); err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+			if err := q.Enqueue(Task{Job: j, Document: printer.Raw("^XA^XZ")}); err != nil {
+				t.Fatalf("got error: %s\n", err)
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) {
+				stored, err := jobs.Get(ctx, j.ID)
+				if err != nil {
+					t.Fatalf("got error: %s\n", err)
+				}
+				if stored.IsFinished() {
+					j = stored
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if j.Status != us.expected {
+				t.Errorf("expected: %v, got: %v (%s)\n", us.expected, j.Status, j.LastError)
+			}
+		})
+	}
+}
+
+// waitFor polls cond until it is true or the timeout is reached.
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition is not met in %v\n", timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestQueuePause(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	sp := &statusPrinter{}
+	go sp.serve(l)
+
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	q := New(printer.New("ZPL", l.Addr().String(), ""), 8, ConnPolicy{StatusInterval: -1}, jobs, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	q.start(ctx, &wg)
+
+	// copies are made by the printer in batches, the job is held between them
+	j := job.New(0, 25, 100*time.Millisecond)
+	if err := jobs.Store(ctx, &j); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if err := q.Enqueue(Task{Job: j, Document: printer.Raw("^XA^XZ")}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	waitFor(t, 5*time.Second, func() bool { return sp.printed.Load() >= 1 })
+	q.Pause()
+	waitFor(t, 5*time.Second, func() bool { return q.State().Current == nil })
+	printed := sp.printed.Load()
+	time.Sleep(300 * time.Millisecond)
+	if n := sp.printed.Load(); n != printed || n >= 25 {
+		t.Errorf("expected no labels printed while paused, got: %d of %d\n", n, printed)
+	}
+	st := q.State()
+	if st.Dispatch != DispatchPaused || st.Depth != 1 {
+		t.Errorf("expected paused queue holding the job, got: %+v\n", st)
+	}
+	held, err := jobs.Get(ctx, j.ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if held.Status != job.StatusQueued || int32(held.Printed) != printed {
+		t.Errorf("expected queued job with %d printed, got: %+v\n", printed, held)
+	}
+
+	// paused queue keeps accepting jobs
+	if err := q.Enqueue(Task{Job: job.Job{ID: 42}}); err != nil {
+		t.Errorf("got error: %s\n", err)
+	}
+	q.Take(func(task Task) bool { return task.Job.ID == 42 })
+
+	q.Resume()
+	waitFor(t, 5*time.Second, func() bool {
+		done, err := jobs.Get(ctx, j.ID)
+		return err == nil && done.Status == job.StatusDone
+	})
+	// the held job continues from the next copy
+	if n := sp.printed.Load(); n != 25 {
+		t.Errorf("expected: %d, got: %d\n", 25, n)
+	}
+}
+
+func TestQueueDrain(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	defer l.Close()
+	sp := &statusPrinter{}
+	go sp.serve(l)
+
+	jobs, err := job.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	q := New(printer.New("ZPL", l.Addr().String(), ""), 8, ConnPolicy{StatusInterval: -1}, jobs, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	q.start(ctx, &wg)
+
+	var enqueued []job.Job
+	for range 2 {
+		j := job.New(0, 3, 50*time.Millisecond)
+		if err := jobs.Store(ctx, &j); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		if err := q.Enqueue(Task{Job: j, Document: printer.Raw("^XA^XZ")}); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+		enqueued = append(enqueued, j)
+	}
+
+	waitFor(t, 5*time.Second, func() bool { return sp.printed.Load() >= 1 })
+	q.Drain()
+	if st := q.State(); st.Dispatch != DispatchDraining {
+		t.Errorf("expected: %v, got: %v\n", DispatchDraining, st.Dispatch)
+	}
+	waitFor(t, 5*time.Second, func() bool { return q.State().Dispatch == DispatchPaused })
+	time.Sleep(300 * time.Millisecond)
+	first, err := jobs.Get(ctx, enqueued[0].ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	second, err := jobs.Get(ctx, enqueued[1].ID)
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if first.Status != job.StatusDone || second.Status != job.StatusQueued || sp.printed.Load() != 3 {
+		t.Errorf("expected only the first job printed, got: %v, %v and %d labels\n", first.Status, second.Status, sp.printed.Load())
+	}
+
+	q.Resume()
+	waitFor(t, 5*time.Second, func() bool {
+		done, err := jobs.Get(ctx, enqueued[1].ID)
+		return err == nil && done.Status == job.StatusDone
+	})
+}
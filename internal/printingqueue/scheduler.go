@@ -0,0 +1,120 @@
+package printingqueue
+
+import (
+	"container/heap"
+	"time"
+)
+
+// scheduler holds tasks that must not be printed before their time, it is
+// owned by the pooler and is not safe for concurrent use.
+type scheduler struct {
+	tasks schedule
+	timer *time.Timer
+}
+
+func newScheduler() *scheduler {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	return &scheduler{timer: timer}
+}
+
+// C fires when the earliest task is due.
+func (s *scheduler) C() <-chan time.Time {
+	return s.timer.C
+}
+
+func (s *scheduler) add(task Task) {
+	heap.Push(&s.tasks, task)
+	s.reset()
+}
+
+// due pops all tasks that are due at the moment.
+func (s *scheduler) due(now time.Time) []Task {
+	ready := []Task{}
+	for len(s.tasks) > 0 && !s.tasks[0].Job.NotBefore.After(now) {
+		ready = append(ready, heap.Pop(&s.tasks).(Task))
+	}
+	s.reset()
+	return ready
+}
+
+func (s *scheduler) remove(jobID int64) bool {
+	for i := range s.tasks {
+		if s.tasks[i].Job.ID == jobID {
+			heap.Remove(&s.tasks, i)
+			s.reset()
+			return true
+		}
+	}
+	return false
+}
+
+// removePrinter removes and returns all tasks of the printer.
+func (s *scheduler) removePrinter(printerID int64) []Task {
+	removed := []Task{}
+	kept := s.tasks[:0]
+	for _, task := range s.tasks {
+		if task.Job.PrinterID == printerID {
+			removed = append(removed, task)
+			continue
+		}
+		kept = append(kept, task)
+	}
+	s.tasks = kept
+	heap.Init(&s.tasks)
+	s.reset()
+	return removed
+}
+
+// move makes matching tasks of the printer due on another printer, it
+// returns moved tasks. Order of tasks does not depend on the printer.
+func (s *scheduler) move(from, to int64, match func(Task) bool) []Task {
+	moved := []Task{}
+	for i := range s.tasks {
+		if s.tasks[i].Job.PrinterID == from && match(s.tasks[i]) {
+			s.tasks[i].Job.PrinterID = to
+			moved = append(moved, s.tasks[i])
+		}
+	}
+	return moved
+}
+
+// count returns number of scheduled tasks per printer.
+func (s *scheduler) count() map[int64]int {
+	counts := map[int64]int{}
+	for i := range s.tasks {
+		counts[s.tasks[i].Job.PrinterID]++
+	}
+	return counts
+}
+
+func (s *scheduler) reset() {
+	s.timer.Stop()
+	if len(s.tasks) > 0 {
+		s.timer.Reset(time.Until(*s.tasks[0].Job.NotBefore))
+	}
+}
+
+// schedule orders tasks by the time they are due.
+type schedule []Task
+
+func (s schedule) Len() int { return len(s) }
+
+func (s schedule) Less(i, j int) bool {
+	if !s[i].Job.NotBefore.Equal(*s[j].Job.NotBefore) {
+		return s[i].Job.NotBefore.Before(*s[j].Job.NotBefore)
+	}
+	return s[i].Job.ID < s[j].Job.ID
+}
+
+func (s schedule) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s *schedule) Push(x any) { *s = append(*s, x.(Task)) }
+
+func (s *schedule) Pop() any {
+	old := *s
+	n := len(old)
+	item := old[n-1]
+	*s = old[:n-1]
+	return item
+}
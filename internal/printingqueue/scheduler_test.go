@@ -0,0 +1,40 @@
+package printingqueue
+
+import (
+	"testing"
+	"time"
+
+	"zhurd/internal/job"
+)
+
+func TestSchedulerDue(t *testing.T) {
+	s := newScheduler()
+	now := time.Now()
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	s.add(Task{Job: job.Job{ID: 1, NotBefore: at(time.Hour)}})
+	s.add(Task{Job: job.Job{ID: 2, NotBefore: at(-time.Minute)}})
+	s.add(Task{Job: job.Job{ID: 3, NotBefore: at(-time.Hour)}})
+	s.add(Task{Job: job.Job{ID: 4, NotBefore: at(time.Minute)}})
+
+	due := s.due(now)
+	if len(due) != 2 {
+		t.Fatalf("expected %d due tasks, got %d\n", 2, len(due))
+	}
+	if due[0].Job.ID != 3 || due[1].Job.ID != 2 {
+		t.Errorf("expected tasks in order [3 2], got [%d %d]\n", due[0].Job.ID, due[1].Job.ID)
+	}
+
+	if !s.remove(4) {
+		t.Errorf("expected task %d to be removed\n", 4)
+	}
+	if s.remove(4) {
+		t.Errorf("expected task %d to be already removed\n", 4)
+	}
+	due = s.due(now.Add(2 * time.Hour))
+	if len(due) != 1 || due[0].Job.ID != 1 {
+		t.Errorf("expected only task %d, got %+v\n", 1, due)
+	}
+}
@@ -0,0 +1,65 @@
+package printingqueue
+
+import (
+	"container/heap"
+	"slices"
+	"sort"
+)
+
+// tasks is a priority queue of tasks, tasks moved to the front go first,
+// the last moved is the first. Other tasks with higher priority go first,
+// tasks with the same priority keep FIFO order.
+type tasks []queuedTask
+
+type queuedTask struct {
+	Task
+	seq   int64
+	front bool
+}
+
+func (t tasks) Len() int { return len(t) }
+
+func (t tasks) Less(i, j int) bool {
+	if t[i].front != t[j].front {
+		return t[i].front
+	}
+	if t[i].front {
+		// seq of moved tasks decreases, so the last moved has the lowest
+		return t[i].seq < t[j].seq
+	}
+	if t[i].Job.Priority != t[j].Job.Priority {
+		return t[i].Job.Priority > t[j].Job.Priority
+	}
+	return t[i].seq < t[j].seq
+}
+
+func (t tasks) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+
+func (t *tasks) Push(x any) { *t = append(*t, x.(queuedTask)) }
+
+func (t *tasks) Pop() any {
+	old := *t
+	n := len(old)
+	item := old[n-1]
+	*t = old[:n-1]
+	return item
+}
+
+// ordered returns tasks in the order they are printed.
+func (t tasks) ordered() tasks {
+	ordered := slices.Clone(t)
+	sort.Sort(ordered)
+	return ordered
+}
+
+// remove deletes the task of the job from the queue, returns false if
+// there is no such task.
+func (t *tasks) remove(jobID int64) bool {
+	for i := range *t {
+		if (*t)[i].Job.ID == jobID {
+			heap.Remove(t, i)
+			return true
+		}
+	}
+	return false
+}
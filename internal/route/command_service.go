@@ -0,0 +1,124 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"zhurd/internal/pool"
+	"zhurd/internal/printer"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	ValidationError = errors.New("Validation error")
+	// ErrUnknownTarget is returned if the target of the route is not registered.
+	ErrUnknownTarget = errors.New("unknown route target")
+)
+
+type StorerDeleter interface {
+	Store(context.Context, *Route) error
+	Update(context.Context, *Route) error
+	Delete(context.Context, int64) error
+}
+
+type PrinterGetter interface {
+	Get(context.Context, int64) (printer.Printer, error)
+}
+
+type CreateRoute struct {
+	Name     string   `json:"name"`
+	Position int      `json:"position"`
+	Station  string   `json:"station"`
+	Zone     string   `json:"zone"`
+	LabelID  int64    `json:"label_id" validate:"min=0"`
+	Language string   `json:"language" validate:"omitempty,oneof=ZPL EPL TSPL"`
+	DPI      int      `json:"dpi" validate:"min=0"`
+	Requires []string `json:"requires" validate:"dive,oneof=cutter rfid"`
+	// exactly one of targets must be set
+	PrinterID int64  `json:"printer_id" validate:"min=0,required_without=PoolID,excluded_with=PoolID"`
+	PoolID    int64  `json:"pool_id" validate:"min=0,required_without=PrinterID"`
+	Comment   string `json:"comment"`
+}
+
+type CommandSvc struct {
+	db       StorerDeleter
+	printers PrinterGetter
+	pools    PoolGetter
+	validate *validator.Validate
+}
+
+func NewCommandSvc(db StorerDeleter, printers PrinterGetter, pools PoolGetter) CommandSvc {
+	return CommandSvc{
+		db:       db,
+		printers: printers,
+		pools:    pools,
+		validate: validator.New(validator.WithRequiredStructEnabled()),
+	}
+}
+
+func (svc CommandSvc) Create(ctx context.Context, cr CreateRoute) (Route, error) {
+	r, err := svc.newRoute(ctx, cr)
+	if err != nil {
+		return Route{}, err
+	}
+
+	if err := svc.db.Store(ctx, &r); err != nil {
+		return Route{}, err
+	}
+	return r, nil
+}
+
+func (svc CommandSvc) Replace(ctx context.Context, routeID int64, cr CreateRoute) (Route, error) {
+	r, err := svc.newRoute(ctx, cr)
+	if err != nil {
+		return Route{}, err
+	}
+	r.ID = routeID
+
+	if err := svc.db.Update(ctx, &r); err != nil {
+		return Route{}, err
+	}
+	return r, nil
+}
+
+func (svc CommandSvc) Delete(ctx context.Context, routeID int64) error {
+	return svc.db.Delete(ctx, routeID)
+}
+
+// newRoute validates the route, its target must be registered.
+func (svc CommandSvc) newRoute(ctx context.Context, cr CreateRoute) (Route, error) {
+	if err := svc.validate.Struct(cr); err != nil {
+		return Route{}, fmt.Errorf("%w: %w", ValidationError, err)
+	}
+	if cr.PrinterID != 0 {
+		if _, err := svc.printers.Get(ctx, cr.PrinterID); err != nil {
+			if errors.Is(err, printer.ErrNotFound) {
+				return Route{}, fmt.Errorf("%w: printer %d", ErrUnknownTarget, cr.PrinterID)
+			}
+			return Route{}, err
+		}
+	}
+	if cr.PoolID != 0 {
+		if _, err := svc.pools.Get(ctx, cr.PoolID); err != nil {
+			if errors.Is(err, pool.ErrNotFound) {
+				return Route{}, fmt.Errorf("%w: pool %d", ErrUnknownTarget, cr.PoolID)
+			}
+			return Route{}, err
+		}
+	}
+	return Route{
+		Name:      cr.Name,
+		Position:  cr.Position,
+		Station:   cr.Station,
+		Zone:      cr.Zone,
+		LabelID:   cr.LabelID,
+		Language:  cr.Language,
+		DPI:       cr.DPI,
+		Requires:  cr.Requires,
+		PrinterID: cr.PrinterID,
+		PoolID:    cr.PoolID,
+		Comment:   cr.Comment,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"zhurd/internal/pool"
+	"zhurd/internal/printer"
+)
+
+func TestCreate(t *testing.T) {
+	printers, err := printer.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if err := printers.Store(context.Background(), &printer.Printer{Addr: "127.0.0.1:9100", Type: "ZPL"}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	pools, err := pool.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	if err := pools.Store(context.Background(), &pool.Pool{Name: "line", PrinterIDs: []int64{1}}); err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+
+	ucs := []struct {
+		desc        string
+		cr          CreateRoute
+		expectedErr error
+	}{
+		{
+			desc:        "to printer",
+			cr:          CreateRoute{Station: "pack-1", PrinterID: 1},
+			expectedErr: nil,
+		},
+		{
+			desc:        "to pool with capabilities",
+			cr:          CreateRoute{Zone: "B", Language: "ZPL", DPI: 300, Requires: []string{"cutter"}, PoolID: 1},
+			expectedErr: nil,
+		},
+		{
+			desc:        "no target",
+			cr:          CreateRoute{Station: "pack-1"},
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "two targets",
+			cr:          CreateRoute{Station: "pack-1", PrinterID: 1, PoolID: 1},
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "unknown option",
+			cr:          CreateRoute{Requires: []string{"laser"}, PrinterID: 1},
+			expectedErr: ValidationError,
+		},
+		{
+			desc:        "unknown printer",
+			cr:          CreateRoute{PrinterID: 2},
+			expectedErr: ErrUnknownTarget,
+		},
+		{
+			desc:        "unknown pool",
+			cr:          CreateRoute{PoolID: 2},
+			expectedErr: ErrUnknownTarget,
+		},
+	}
+
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	svc := NewCommandSvc(repo, printers, pools)
+	for _, uc := range ucs {
+		t.Run(uc.desc, func(t *testing.T) {
+			_, err := svc.Create(context.Background(), uc.cr)
+			if !errors.Is(err, uc.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", uc.expectedErr, err)
+			}
+		})
+	}
+}
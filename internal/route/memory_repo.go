@@ -0,0 +1,102 @@
+package route
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+)
+
+var (
+	ErrNotFound = errors.New("record not found")
+)
+
+type Memory struct {
+	m      map[int64][]byte
+	nextID int64
+	mu     sync.RWMutex
+}
+
+func NewMemory() (*Memory, error) {
+	return &Memory{
+		m:      make(map[int64][]byte),
+		nextID: 1,
+		mu:     sync.RWMutex{},
+	}, nil
+}
+
+func (m *Memory) Store(ctx context.Context, r *Route) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r.ID == 0 {
+		if _, ok := m.m[m.nextID]; ok {
+			panic("could not generate unique ID for route")
+		}
+		r.ID = m.nextID
+		m.nextID += 1
+	}
+	return m.put(*r)
+}
+
+func (m *Memory) Update(ctx context.Context, r *Route) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.m[r.ID]; !ok {
+		return ErrNotFound
+	}
+	return m.put(*r)
+}
+
+func (m *Memory) put(r Route) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	m.m[r.ID] = data
+	return nil
+}
+
+func (m *Memory) Get(ctx context.Context, id int64) (Route, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.m[id]
+	if !ok {
+		return Route{}, ErrNotFound
+	}
+	var r Route
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Route{}, err
+	}
+	return r, nil
+}
+
+// List returns routes ordered by position, routes with the same position
+// keep the order they were created in.
+func (m *Memory) List(ctx context.Context) ([]Route, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	routes := make([]Route, 0, len(m.m))
+	for _, val := range m.m {
+		var r Route
+		if err := json.Unmarshal(val, &r); err != nil {
+			return nil, err
+		}
+		routes = append(routes, r)
+	}
+	slices.SortFunc(routes, func(a, b Route) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.ID, b.ID))
+	})
+	return routes, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.m[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.m, id)
+	return nil
+}
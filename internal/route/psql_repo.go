@@ -0,0 +1,144 @@
+package route
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// foreignKeyViolation is the code of postgres error.
+const foreignKeyViolation = "23503"
+
+const routeColumns = `id, name, position, station, zone, label_id, language, dpi, requires,
+	printer_id, pool_id, comment`
+
+type PSQL struct {
+	pool *pgxpool.Pool
+}
+
+func NewPSQL(pool *pgxpool.Pool) (*PSQL, error) {
+	return &PSQL{pool: pool}, nil
+}
+
+func (repo *PSQL) Store(ctx context.Context, r *Route) error {
+	sql := `INSERT INTO routes (name, position, station, zone, label_id, language, dpi, requires,
+		printer_id, pool_id, comment)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	row := repo.pool.QueryRow(ctx, sql,
+		r.Name, r.Position, r.Station, r.Zone, nullID(r.LabelID), r.Language, r.DPI, requires(r.Requires),
+		nullID(r.PrinterID), nullID(r.PoolID), r.Comment,
+	)
+	if err := row.Scan(&r.ID); err != nil {
+		return mapError(err)
+	}
+	return nil
+}
+
+func (repo *PSQL) Update(ctx context.Context, r *Route) error {
+	sql := `UPDATE routes SET name = $2, position = $3, station = $4, zone = $5, label_id = $6, language = $7,
+		dpi = $8, requires = $9, printer_id = $10, pool_id = $11, comment = $12 WHERE id = $1`
+	tag, err := repo.pool.Exec(ctx, sql, r.ID,
+		r.Name, r.Position, r.Station, r.Zone, nullID(r.LabelID), r.Language, r.DPI, requires(r.Requires),
+		nullID(r.PrinterID), nullID(r.PoolID), r.Comment,
+	)
+	if err != nil {
+		return mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (repo *PSQL) Get(ctx context.Context, id int64) (Route, error) {
+	sql := "SELECT " + routeColumns + " FROM routes WHERE id = $1"
+	r, err := scanRoute(repo.pool.QueryRow(ctx, sql, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Route{}, ErrNotFound
+		}
+		return Route{}, err
+	}
+	return r, nil
+}
+
+// List returns routes ordered by position, routes with the same position
+// keep the order they were created in.
+func (repo *PSQL) List(ctx context.Context) ([]Route, error) {
+	sql := "SELECT " + routeColumns + " FROM routes ORDER BY position, id"
+	rows, err := repo.pool.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	routes := []Route{}
+	for rows.Next() {
+		r, err := scanRoute(rows)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, r)
+	}
+	return routes, rows.Err()
+}
+
+func (repo *PSQL) Delete(ctx context.Context, id int64) error {
+	sql := "DELETE FROM routes WHERE id = $1"
+	tag, err := repo.pool.Exec(ctx, sql, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanRoute(row pgx.Row) (Route, error) {
+	r := Route{}
+	var labelID, printerID, poolID *int64
+	if err := row.Scan(
+		&r.ID, &r.Name, &r.Position, &r.Station, &r.Zone, &labelID, &r.Language, &r.DPI, &r.Requires,
+		&printerID, &poolID, &r.Comment,
+	); err != nil {
+		return Route{}, err
+	}
+	r.LabelID = deref(labelID)
+	r.PrinterID = deref(printerID)
+	r.PoolID = deref(poolID)
+	return r, nil
+}
+
+// nullID stores zero ID as NULL, so foreign keys are not checked for it.
+func nullID(id int64) *int64 {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+func deref(id *int64) int64 {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+func requires(options []string) []string {
+	if options == nil {
+		return []string{}
+	}
+	return options
+}
+
+// mapError reports removed label or target of the route as unknown target.
+func mapError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return ErrUnknownTarget
+	}
+	return err
+}
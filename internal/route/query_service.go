@@ -0,0 +1,91 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"zhurd/internal/pool"
+	"zhurd/internal/printer"
+)
+
+var ErrNoRoute = errors.New("no route matches request")
+
+// GetterLister lists routes in the order of evaluation.
+type GetterLister interface {
+	Get(context.Context, int64) (Route, error)
+	List(context.Context) ([]Route, error)
+}
+
+type PoolGetter interface {
+	Get(context.Context, int64) (pool.Pool, error)
+}
+
+type QuerySvc struct {
+	db       GetterLister
+	printers PrinterGetter
+	pools    PoolGetter
+}
+
+func NewQuerySvc(db GetterLister, printers PrinterGetter, pools PoolGetter) QuerySvc {
+	return QuerySvc{db: db, printers: printers, pools: pools}
+}
+
+func (svc QuerySvc) Get(ctx context.Context, routeID int64) (Route, error) {
+	return svc.db.Get(ctx, routeID)
+}
+
+func (svc QuerySvc) List(ctx context.Context) ([]Route, error) {
+	return svc.db.List(ctx)
+}
+
+// Resolve returns the target of the first route that matches the request
+// and whose target has capabilities the route requires.
+func (svc QuerySvc) Resolve(ctx context.Context, req Request) (Target, error) {
+	routes, err := svc.db.List(ctx)
+	if err != nil {
+		return Target{}, err
+	}
+	for _, r := range routes {
+		if !r.Matches(req) {
+			continue
+		}
+		fits, err := svc.targetFits(ctx, r)
+		if err != nil {
+			return Target{}, err
+		}
+		if fits {
+			return Target{RouteID: r.ID, PrinterID: r.PrinterID, PoolID: r.PoolID}, nil
+		}
+	}
+	return Target{}, fmt.Errorf("%w: station %q, zone %q, label %d", ErrNoRoute, req.Station, req.Zone, req.LabelID)
+}
+
+// targetFits checks capabilities of the target printer or of all members
+// of the target pool, removed target does not fit.
+func (svc QuerySvc) targetFits(ctx context.Context, r Route) (bool, error) {
+	printerIDs := []int64{r.PrinterID}
+	if r.PoolID != 0 {
+		pl, err := svc.pools.Get(ctx, r.PoolID)
+		if errors.Is(err, pool.ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		printerIDs = pl.PrinterIDs
+	}
+	for _, printerID := range printerIDs {
+		p, err := svc.printers.Get(ctx, printerID)
+		if errors.Is(err, printer.ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !r.Fits(p.Capabilities) {
+			return false, nil
+		}
+	}
+	return len(printerIDs) > 0, nil
+}
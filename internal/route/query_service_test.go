@@ -0,0 +1,103 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"zhurd/internal/pool"
+	"zhurd/internal/printer"
+)
+
+func TestResolve(t *testing.T) {
+	printers, err := printer.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	cutter := &printer.Capabilities{Language: "ZPL", DPI: 203, Options: []string{printer.OptionCutter}}
+	for _, p := range []printer.Printer{
+		{Addr: "127.0.0.1:9100", Type: "ZPL", Capabilities: cutter},
+		{Addr: "127.0.0.1:9101", Type: "ZPL"},
+		{Addr: "127.0.0.1:9102", Type: "ZPL", Capabilities: cutter},
+	} {
+		if err := printers.Store(context.Background(), &p); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+	pools, err := pool.NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	for _, pl := range []pool.Pool{
+		{Name: "mixed", PrinterIDs: []int64{1, 2}},
+		{Name: "cutters", PrinterIDs: []int64{1, 3}},
+	} {
+		if err := pools.Store(context.Background(), &pl); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+
+	repo, err := NewMemory()
+	if err != nil {
+		t.Fatalf("got error: %s\n", err)
+	}
+	for _, r := range []Route{
+		{Position: 10, Zone: "A", PrinterID: 2},
+		// the same position keeps the order of creation
+		{Position: 10, Zone: "A", PrinterID: 1},
+		{Position: 0, Station: "pack-1", LabelID: 5, PrinterID: 3},
+		{Position: 20, Zone: "B", Requires: []string{printer.OptionCutter}, PoolID: 1},
+		{Position: 21, Zone: "B", Requires: []string{printer.OptionCutter}, PoolID: 2},
+		{Position: 30, Zone: "C", Requires: []string{printer.OptionCutter}, PrinterID: 2},
+	} {
+		if err := repo.Store(context.Background(), &r); err != nil {
+			t.Fatalf("got error: %s\n", err)
+		}
+	}
+
+	ucs := []struct {
+		desc        string
+		req         Request
+		expected    Target
+		expectedErr error
+	}{
+		{
+			desc:     "zone",
+			req:      Request{Station: "pack-2", Zone: "A", LabelID: 5},
+			expected: Target{RouteID: 1, PrinterID: 2},
+		},
+		{
+			desc:     "station and label go first by position",
+			req:      Request{Station: "pack-1", Zone: "A", LabelID: 5},
+			expected: Target{RouteID: 3, PrinterID: 3},
+		},
+		{
+			desc:     "pool without cutter on every member is skipped",
+			req:      Request{Zone: "B"},
+			expected: Target{RouteID: 5, PoolID: 2},
+		},
+		{
+			desc:        "printer with unknown capabilities does not fit",
+			req:         Request{Zone: "C"},
+			expectedErr: ErrNoRoute,
+		},
+		{
+			desc:        "no match",
+			req:         Request{Zone: "D"},
+			expectedErr: ErrNoRoute,
+		},
+	}
+
+	svc := NewQuerySvc(repo, printers, pools)
+	for _, uc := range ucs {
+		t.Run(uc.desc, func(t *testing.T) {
+			target, err := svc.Resolve(context.Background(), uc.req)
+			if !errors.Is(err, uc.expectedErr) {
+				t.Errorf("expected: %v, got: %v\n", uc.expectedErr, err)
+			}
+			if target != uc.expected {
+				t.Errorf("expected: %+v, got: %+v\n", uc.expected, target)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package route
+
+import (
+	"slices"
+
+	"zhurd/internal/printer"
+)
+
+// Route is a rule that resolves enqueue request to a printer or a pool.
+// Empty attributes of the rule match any request, capabilities of the
+// rule are checked against the target, all members of the target pool
+// must have them. Routes are evaluated by position, the first match wins.
+type Route struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+	// request attributes
+	Station string `json:"station"`
+	Zone    string `json:"zone"`
+	LabelID int64  `json:"label_id"`
+	// capabilities of the target
+	Language string   `json:"language"`
+	DPI      int      `json:"dpi"`
+	Requires []string `json:"requires"`
+	// target, exactly one is set
+	PrinterID int64  `json:"printer_id"`
+	PoolID    int64  `json:"pool_id"`
+	Comment   string `json:"comment"`
+}
+
+// Request holds attributes of enqueue request that routes match.
+type Request struct {
+	Station string `json:"station"`
+	Zone    string `json:"zone"`
+	LabelID int64  `json:"label_id"`
+}
+
+// Target is the printer or the pool the request is routed to.
+type Target struct {
+	RouteID   int64 `json:"route_id"`
+	PrinterID int64 `json:"printer_id,omitempty"`
+	PoolID    int64 `json:"pool_id,omitempty"`
+}
+
+// Matches reports whether attributes of the request match the route.
+func (r Route) Matches(req Request) bool {
+	return (r.Station == "" || r.Station == req.Station) &&
+		(r.Zone == "" || r.Zone == req.Zone) &&
+		(r.LabelID == 0 || r.LabelID == req.LabelID)
+}
+
+// Fits reports whether the printer has capabilities the route requires,
+// printer with unknown capabilities fits only the route without them.
+func (r Route) Fits(c *printer.Capabilities) bool {
+	if r.Language == "" && r.DPI == 0 && len(r.Requires) == 0 {
+		return true
+	}
+	if c == nil {
+		return false
+	}
+	if r.Language != "" && r.Language != c.Language {
+		return false
+	}
+	if r.DPI != 0 && r.DPI != c.DPI {
+		return false
+	}
+	return !slices.ContainsFunc(r.Requires, func(option string) bool {
+		return !c.Has(option)
+	})
+}